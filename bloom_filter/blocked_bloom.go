@@ -0,0 +1,124 @@
+package bloomfilter
+
+import "math"
+
+// BLOCK_BITS is one 64-byte cache line.
+const BLOCK_BITS = 512
+
+type bloomBlock [BLOCK_BITS / 64]uint64
+
+// BlockedBloomFilter trades a little accuracy for lookup speed
+// ("Cache-, Hash- and Space-Efficient Bloom Filters", Putze, Sanders, Singler, 2007).
+//
+// A key is hashed to one 512-bit block first, then all its k bits are set inside that block.
+// A lookup touches a single cache line instead of k random ones, which is what dominates
+// the classic filter time once it is larger than CPU caches.
+//
+// False positive rate vs the classic layout: blocks get unequal numbers of keys (Poisson around the average),
+// and the overloaded blocks answer "yes" more often than the light ones compensate for.
+// At the same memory, measured by TestBlockedBloomFilter_FalsePositiveRate (10 bits per key, k = 7):
+//
+//	classic (SizedBloomFilter)   ~0.8%
+//	blocked, 512-bit blocks      ~0.96%
+//
+// I.e. about 1 extra bit per key restores the classic rate. FPRate estimates the blocked rate
+// by summing over the Poisson distribution of keys per block (predicted 0.95% for the case above).
+type BlockedBloomFilter struct {
+	blocks []bloomBlock
+	k      int
+}
+
+// NewBlockedBloomFilter sizes the filter like SizedBloomFilter (m rounded up to whole blocks)
+// for n expected items and target rate p; the real rate is a bit higher, see FPRate.
+// Time: O(m/64)
+// Space: O(m/64)
+func NewBlockedBloomFilter(n int, p float64) (*BlockedBloomFilter, error) {
+	m, k, err := OptimalParams(n, p)
+	if err != nil {
+		return nil, err
+	}
+	return NewBlockedBloomFilterMK(m, k)
+}
+
+// Time: O(m/64)
+// Space: O(m/64)
+func NewBlockedBloomFilterMK(m uint64, k int) (*BlockedBloomFilter, error) {
+	if m == 0 || k <= 0 || k > MAX_HASHES {
+		return nil, ErrInvalidParams
+	}
+	return &BlockedBloomFilter{
+		blocks: make([]bloomBlock, (m+BLOCK_BITS-1)/BLOCK_BITS),
+		k:      k,
+	}, nil
+}
+
+// h1 picks the block, h2 is cut into 9-bit bit positions inside it (7 per 64-bit hash, remixed for more).
+// The low bit of h2 is dropped: baseHashes forces it to 1, which would put the first probe on odd bits only.
+// Double hashing (a + i*b) % 512, as in SizedBloomFilter, is too correlated here:
+// with only 512 slots it leaves a few hundred thousand distinct bit patterns, and the FP rate goes up by ~25%.
+// Time: O(len(s))
+// Space: O(1)
+func (bf *BlockedBloomFilter) locate(s string) (*bloomBlock, uint64) {
+	h1, h2 := baseHashes(s)
+	return &bf.blocks[h1%uint64(len(bf.blocks))], h2 >> 1
+}
+
+const positionsPerHash = 64 / 9
+
+// Time: O(len(s) + k)
+// Space: O(1)
+func (bf *BlockedBloomFilter) Add(s string) {
+	block, h := bf.locate(s)
+	for i := range bf.k {
+		if i > 0 && i%positionsPerHash == 0 {
+			h = mix64(h)
+		}
+		pos := (h >> (9 * (i % positionsPerHash))) % BLOCK_BITS
+		block[pos/64] |= 1 << (pos % 64)
+	}
+}
+
+// Time: O(len(s) + k), a single cache line is read
+// Space: O(1)
+func (bf *BlockedBloomFilter) IsValue(s string) bool {
+	block, h := bf.locate(s)
+	for i := range bf.k {
+		if i > 0 && i%positionsPerHash == 0 {
+			h = mix64(h)
+		}
+		pos := (h >> (9 * (i % positionsPerHash))) % BLOCK_BITS
+		if block[pos/64]&(1<<(pos%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// BitLen returns the number of bits in the filter, a multiple of BLOCK_BITS.
+func (bf *BlockedBloomFilter) BitLen() uint64 {
+	return uint64(len(bf.blocks)) * BLOCK_BITS
+}
+
+// K returns the number of bits set per key.
+func (bf *BlockedBloomFilter) K() int {
+	return bf.k
+}
+
+// FPRate returns the expected false positive rate after n distinct insertions:
+// sum over j of P(block holds j keys) * (1 - e^(-kj/512))^k, j ~ Poisson(n / blocks).
+// Time: O(λ + sqrt(λ)) terms, λ = keys per block
+// Space: O(1)
+func (bf *BlockedBloomFilter) FPRate(n int) float64 {
+	lambda := float64(n) / float64(len(bf.blocks))
+	k := float64(bf.k)
+
+	// the terms beyond λ + 10σ are negligible
+	limit := int(lambda + 10*math.Sqrt(lambda) + 10)
+	var rate float64
+	for j := 0; j <= limit; j++ {
+		lg, _ := math.Lgamma(float64(j) + 1)
+		poisson := math.Exp(float64(j)*math.Log(lambda) - lambda - lg)
+		rate += poisson * math.Pow(1-math.Exp(-k*float64(j)/BLOCK_BITS), k)
+	}
+	return rate
+}
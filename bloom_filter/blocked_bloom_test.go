@@ -0,0 +1,177 @@
+package bloomfilter
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+)
+
+const benchKeys = 1_000_000
+
+func TestBlockedBloomFilter_NoFalseNegatives(t *testing.T) {
+	bf, err := NewBlockedBloomFilter(10_000, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 10_000 {
+		bf.Add(fmt.Sprintf("key-%d", i))
+	}
+	for i := range 10_000 {
+		if !bf.IsValue(fmt.Sprintf("key-%d", i)) {
+			t.Fatalf("key-%d not found after Add", i)
+		}
+	}
+
+	if bf.BitLen()%BLOCK_BITS != 0 {
+		t.Errorf("BitLen %d is not a multiple of %d", bf.BitLen(), BLOCK_BITS)
+	}
+	if _, err := NewBlockedBloomFilterMK(0, 3); !errors.Is(err, ErrInvalidParams) {
+		t.Errorf("err = %v, expected ErrInvalidParams", err)
+	}
+}
+
+func TestBlockedBloomFilter_KeyStaysInOneBlock(t *testing.T) {
+	bf, _ := NewBlockedBloomFilterMK(64*BLOCK_BITS, 7)
+	bf.Add("0123456789")
+
+	touched := 0
+	for _, block := range bf.blocks {
+		if block != (bloomBlock{}) {
+			touched++
+		}
+	}
+	if touched != 1 {
+		t.Errorf("key set bits in %d blocks, expected 1", touched)
+	}
+}
+
+// Same memory for both layouts: 10 bits per key, k = 7.
+func TestBlockedBloomFilter_FalsePositiveRate(t *testing.T) {
+	const n, bitsPerKey, k = 200_000, 10, 7
+	blocked, _ := NewBlockedBloomFilterMK(n*bitsPerKey, k)
+	classic, _ := NewSizedBloomFilterMK(blocked.BitLen(), k)
+
+	for i := range n {
+		blocked.Add(fmt.Sprintf("member_%d", i))
+		classic.Add(fmt.Sprintf("member_%d", i))
+	}
+
+	const probes = 200_000
+	blockedFP, classicFP := 0, 0
+	for i := range probes {
+		s := fmt.Sprintf("candidate_%d", i)
+		if blocked.IsValue(s) {
+			blockedFP++
+		}
+		if classic.IsValue(s) {
+			classicFP++
+		}
+	}
+
+	blockedRate := float64(blockedFP) / probes
+	classicRate := float64(classicFP) / probes
+	t.Logf("classic: %d false positives (%.4f, predicted %.4f)", classicFP, classicRate, classic.FPRate(n))
+	t.Logf("blocked: %d false positives (%.4f, predicted %.4f)", blockedFP, blockedRate, blocked.FPRate(n))
+
+	if math.Abs(blockedRate-blocked.FPRate(n)) > 0.1*blocked.FPRate(n) {
+		t.Errorf("measured rate %.4f far from predicted %.4f", blockedRate, blocked.FPRate(n))
+	}
+	if blockedRate > 1.5*classicRate {
+		t.Errorf("blocked rate %.4f is much worse than classic %.4f", blockedRate, classicRate)
+	}
+}
+
+func benchStrings() []string {
+	keys := make([]string, benchKeys)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key_%d", i)
+	}
+	return keys
+}
+
+// The lab filters can't really hold 1M keys (BloomFilter has 32 bits), so their numbers show
+// only the hashing and access cost, the answers are mostly "yes". CountingBloomFilter gets
+// the same 10 slots per key as the others.
+
+func BenchmarkIsValue_1M_BloomFilter(b *testing.B) {
+	keys := benchStrings()
+	bf := NewBloomFilter(32)
+	for _, s := range keys {
+		bf.Add(s)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bf.IsValue(keys[i%benchKeys])
+	}
+}
+
+func BenchmarkIsValue_1M_CountingBloomFilter(b *testing.B) {
+	keys := benchStrings()
+	cbf := NewCountingBloomFilter(10 * benchKeys)
+	for _, s := range keys {
+		cbf.Add(s)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cbf.IsValue(keys[i%benchKeys])
+	}
+}
+
+func BenchmarkIsValue_1M_SizedBloomFilter(b *testing.B) {
+	keys := benchStrings()
+	bf, _ := NewSizedBloomFilterMK(10*benchKeys, 7)
+	for _, s := range keys {
+		bf.Add(s)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bf.IsValue(keys[i%benchKeys])
+	}
+}
+
+func BenchmarkIsValue_1M_BlockedBloomFilter(b *testing.B) {
+	keys := benchStrings()
+	bf, _ := NewBlockedBloomFilterMK(10*benchKeys, 7)
+	for _, s := range keys {
+		bf.Add(s)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bf.IsValue(keys[i%benchKeys])
+	}
+}
+
+func BenchmarkAdd_1M_BloomFilter(b *testing.B) {
+	keys := benchStrings()
+	bf := NewBloomFilter(32)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bf.Add(keys[i%benchKeys])
+	}
+}
+
+func BenchmarkAdd_1M_CountingBloomFilter(b *testing.B) {
+	keys := benchStrings()
+	cbf := NewCountingBloomFilter(10 * benchKeys)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cbf.Add(keys[i%benchKeys])
+	}
+}
+
+func BenchmarkAdd_1M_BlockedBloomFilter(b *testing.B) {
+	keys := benchStrings()
+	bf, _ := NewBlockedBloomFilterMK(10*benchKeys, 7)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bf.Add(keys[i%benchKeys])
+	}
+}
@@ -13,6 +13,8 @@ const HEX_31 = 0x1F
 const MAGIC_1 = 17
 const MAGIC_2 = 223
 
+// BloomFilter is the lab-task filter: a single 32-bit mask and two fixed hashes,
+// so filter_len above 32 does not work. See SizedBloomFilter for the general one.
 type BloomFilter struct {
 	filter_len int
 	bitmask    uint32
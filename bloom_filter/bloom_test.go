@@ -1,7 +1,9 @@
 package bloomfilter
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"testing"
 )
 
@@ -358,8 +360,20 @@ func BenchmarkHash2(b *testing.B) {
 }
 
 // merge filters
+func mustMerge(t *testing.T, filters ...*BloomFilter) *BloomFilter {
+	t.Helper()
+	merged, err := Merge(filters...)
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	return merged
+}
+
 func TestMerge_EmptyInput(t *testing.T) {
-	result := Merge()
+	result, err := Merge[*BloomFilter]()
+	if err != nil {
+		t.Fatalf("Merge() with no arguments failed: %v", err)
+	}
 	if result != nil {
 		t.Error("Merge() with no arguments should return nil")
 	}
@@ -369,7 +383,7 @@ func TestMerge_SingleFilter(t *testing.T) {
 	bf := NewBloomFilter(32)
 	bf.Add("hello")
 
-	merged := Merge(bf)
+	merged := mustMerge(t, bf)
 
 	if merged.bitmask != bf.bitmask {
 		t.Errorf("merged bitmask %032b != original %032b", merged.bitmask, bf.bitmask)
@@ -386,7 +400,7 @@ func TestMerge_TwoFilters(t *testing.T) {
 	bf2 := NewBloomFilter(32)
 	bf2.Add("dog")
 
-	merged := Merge(bf1, bf2)
+	merged := mustMerge(t, bf1, bf2)
 
 	if !merged.IsValue("cat") {
 		t.Error("merged filter should contain 'cat'")
@@ -406,7 +420,7 @@ func TestMerge_ThreeFilters(t *testing.T) {
 	bf3 := NewBloomFilter(32)
 	bf3.Add("three")
 
-	merged := Merge(bf1, bf2, bf3)
+	merged := mustMerge(t, bf1, bf2, bf3)
 
 	for _, s := range []string{"one", "two", "three"} {
 		if !merged.IsValue(s) {
@@ -422,7 +436,7 @@ func TestMerge_BitwiseOR(t *testing.T) {
 	bf2 := NewBloomFilter(32)
 	bf2.bitmask = 0b11110000
 
-	merged := Merge(bf1, bf2)
+	merged := mustMerge(t, bf1, bf2)
 
 	expected := uint32(0b11111111)
 	if merged.bitmask != expected {
@@ -437,7 +451,7 @@ func TestMerge_OverlappingBits(t *testing.T) {
 	bf2 := NewBloomFilter(32)
 	bf2.bitmask = 0b00011110
 
-	merged := Merge(bf1, bf2)
+	merged := mustMerge(t, bf1, bf2)
 
 	expected := uint32(0b00111110)
 	if merged.bitmask != expected {
@@ -449,7 +463,7 @@ func TestMerge_PreservesFilterLen(t *testing.T) {
 	bf1 := NewBloomFilter(32)
 	bf2 := NewBloomFilter(32)
 
-	merged := Merge(bf1, bf2)
+	merged := mustMerge(t, bf1, bf2)
 
 	if merged.filter_len != 32 {
 		t.Errorf("merged filter_len %d != 32", merged.filter_len)
@@ -460,7 +474,7 @@ func TestMerge_EmptyFilters(t *testing.T) {
 	bf1 := NewBloomFilter(32)
 	bf2 := NewBloomFilter(32)
 
-	merged := Merge(bf1, bf2)
+	merged := mustMerge(t, bf1, bf2)
 
 	if merged.bitmask != 0 {
 		t.Errorf("merging empty filters should give bitmask 0, got %032b", merged.bitmask)
@@ -476,7 +490,7 @@ func TestMerge_DoesNotModifyOriginals(t *testing.T) {
 	bf2.Add("dog")
 	original2 := bf2.bitmask
 
-	Merge(bf1, bf2)
+	mustMerge(t, bf1, bf2)
 
 	if bf1.bitmask != original1 {
 		t.Error("Merge modified bf1")
@@ -497,7 +511,7 @@ func TestMerge_NoFalseNegatives(t *testing.T) {
 		allStrings = append(allStrings, s)
 	}
 
-	merged := Merge(filters...)
+	merged := mustMerge(t, filters...)
 
 	for _, s := range allStrings {
 		if !merged.IsValue(s) {
@@ -519,7 +533,7 @@ func TestMerge_IncreasedFalsePositives(t *testing.T) {
 	}
 	bits2 := countBits(bf2.bitmask)
 
-	merged := Merge(bf1, bf2)
+	merged := mustMerge(t, bf1, bf2)
 	bitsMerged := countBits(merged.bitmask)
 
 	t.Logf("bf1 bits: %d, bf2 bits: %d, merged bits: %d", bits1, bits2, bitsMerged)
@@ -550,7 +564,7 @@ func TestMerge_DistributedScenario(t *testing.T) {
 	nodeC := NewBloomFilter(32)
 	nodeC.Add("user_5")
 
-	global := Merge(nodeA, nodeB, nodeC)
+	global := mustMerge(t, nodeA, nodeB, nodeC)
 
 	for i := 1; i <= 5; i++ {
 		user := fmt.Sprintf("user_%d", i)
@@ -563,6 +577,199 @@ func TestMerge_DistributedScenario(t *testing.T) {
 	t.Logf("Bits set: %d/32", countBits(global.bitmask))
 }
 
+func TestMerge_LengthMismatch(t *testing.T) {
+	_, err := Merge(NewBloomFilter(32), NewBloomFilter(16))
+	if !errors.Is(err, ErrFilterMismatch) {
+		t.Errorf("Merge of 32- and 16-bit filters returned %v, expected ErrFilterMismatch", err)
+	}
+}
+
+func TestMerge_SizedFilters(t *testing.T) {
+	a, _ := NewSizedBloomFilter(1000, 0.01)
+	b, _ := NewSizedBloomFilter(1000, 0.01)
+	for i := range 500 {
+		a.Add(fmt.Sprintf("a_%d", i))
+		b.Add(fmt.Sprintf("b_%d", i))
+	}
+
+	merged, err := Merge(a, b)
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	for i := range 500 {
+		if !merged.IsValue(fmt.Sprintf("a_%d", i)) || !merged.IsValue(fmt.Sprintf("b_%d", i)) {
+			t.Fatalf("merged filter lost key %d", i)
+		}
+	}
+	if a.IsValue("b_0") && a.IsValue("b_1") && a.IsValue("b_2") {
+		t.Error("Merge modified its first argument")
+	}
+}
+
+func TestMerge_SizedMismatch(t *testing.T) {
+	base, _ := NewSizedBloomFilterMK(1024, 3)
+	otherK, _ := NewSizedBloomFilterMK(1024, 4)
+	otherM, _ := NewSizedBloomFilterMK(2048, 3)
+	lab := newLabSizedBloomFilter(1024)
+	double, _ := NewSizedBloomFilterMK(1024, 2)
+
+	cases := []struct {
+		name string
+		a, b *SizedBloomFilter
+	}{
+		{"different k", base, otherK},
+		{"different m", base, otherM},
+		{"different hash scheme", double, lab},
+	}
+	for _, tc := range cases {
+		if _, err := Merge(tc.a, tc.b); !errors.Is(err, ErrFilterMismatch) {
+			t.Errorf("%s: Merge returned %v, expected ErrFilterMismatch", tc.name, err)
+		}
+		if _, err := Intersect(tc.a, tc.b); !errors.Is(err, ErrFilterMismatch) {
+			t.Errorf("%s: Intersect returned %v, expected ErrFilterMismatch", tc.name, err)
+		}
+	}
+}
+
+// filter algebra
+func TestIntersect_KeepsCommonKeys(t *testing.T) {
+	a, _ := NewSizedBloomFilter(1000, 0.01)
+	b, _ := NewSizedBloomFilter(1000, 0.01)
+	for i := range 300 {
+		a.Add(fmt.Sprintf("key_%d", i))
+	}
+	for i := 200; i < 500; i++ {
+		b.Add(fmt.Sprintf("key_%d", i))
+	}
+
+	both, err := Intersect(a, b)
+	if err != nil {
+		t.Fatalf("Intersect failed: %v", err)
+	}
+	for i := 200; i < 300; i++ {
+		if key := fmt.Sprintf("key_%d", i); !both.IsValue(key) {
+			t.Errorf("intersection should contain %q", key)
+		}
+	}
+
+	onlyOne := 0
+	for i := range 200 {
+		if both.IsValue(fmt.Sprintf("key_%d", i)) {
+			onlyOne++
+		}
+	}
+	if onlyOne > 20 {
+		t.Errorf("%d of 200 keys from a only passed the intersection", onlyOne)
+	}
+}
+
+func TestIntersect_BloomFilter(t *testing.T) {
+	bf1 := NewBloomFilter(32)
+	bf1.Add("cat")
+	bf1.Add("dog")
+	bf2 := NewBloomFilter(32)
+	bf2.Add("dog")
+
+	both, err := Intersect(bf1, bf2)
+	if err != nil {
+		t.Fatalf("Intersect failed: %v", err)
+	}
+	if both.bitmask != bf1.bitmask&bf2.bitmask {
+		t.Errorf("intersection bitmask %032b, expected %032b", both.bitmask, bf1.bitmask&bf2.bitmask)
+	}
+	if !both.IsValue("dog") {
+		t.Error("intersection should contain 'dog'")
+	}
+}
+
+func TestEstimateCount(t *testing.T) {
+	bf, _ := NewSizedBloomFilter(10000, 0.01)
+	if got := bf.EstimateCount(); got != 0 {
+		t.Errorf("empty filter estimate is %f, expected 0", got)
+	}
+
+	for _, n := range []int{100, 1000, 5000, 10000} {
+		for i := range n {
+			bf.Add(fmt.Sprintf("key_%d", i))
+		}
+		got := bf.EstimateCount()
+		if got < float64(n)*0.95 || got > float64(n)*1.05 {
+			t.Errorf("after %d keys the estimate is %.1f, expected within 5%%", n, got)
+		}
+	}
+}
+
+func TestEstimateCount_Saturated(t *testing.T) {
+	bf := NewBloomFilter(32)
+	bf.bitmask = ^uint32(0)
+	if got := bf.EstimateCount(); !math.IsInf(got, 1) {
+		t.Errorf("saturated filter estimate is %f, expected +Inf", got)
+	}
+
+	bf = NewBloomFilter(32)
+	bf.Add("hello")
+	if got := bf.EstimateCount(); got < 0.5 || got > 1.5 {
+		t.Errorf("one key estimate is %f, expected about 1", got)
+	}
+}
+
+func TestFilterCandidates(t *testing.T) {
+	bf, _ := NewSizedBloomFilter(1000, 0.01)
+	members := make(map[string]bool)
+	for i := 0; i < 2000; i += 4 {
+		key := fmt.Sprintf("user_%d", i)
+		bf.Add(key)
+		members[key] = true
+	}
+
+	candidates := func(yield func(string) bool) {
+		for i := range 2000 {
+			if !yield(fmt.Sprintf("user_%d", i)) {
+				return
+			}
+		}
+	}
+
+	found := make(map[string]bool)
+	falsePositives := 0
+	for key := range FilterCandidates(bf, candidates) {
+		found[key] = true
+		if !members[key] {
+			falsePositives++
+		}
+	}
+	for key := range members {
+		if !found[key] {
+			t.Errorf("member %q was not streamed", key)
+		}
+	}
+	if falsePositives > 30 {
+		t.Errorf("%d of 1500 non-members streamed, expected about 1%%", falsePositives)
+	}
+}
+
+func TestFilterCandidates_StopsEarly(t *testing.T) {
+	bf := NewBloomFilter(32)
+	bf.Add("a")
+	bf.Add("b")
+
+	pulled := 0
+	candidates := func(yield func(string) bool) {
+		for _, s := range []string{"a", "b", "c"} {
+			pulled++
+			if !yield(s) {
+				return
+			}
+		}
+	}
+	for range FilterCandidates(bf, candidates) {
+		break
+	}
+	if pulled != 1 {
+		t.Errorf("pulled %d candidates after break, expected 1", pulled)
+	}
+}
+
 // deletion bloom
 
 func TestCountingBloomFilter_New(t *testing.T) {
@@ -570,11 +777,11 @@ func TestCountingBloomFilter_New(t *testing.T) {
 	if cbf.filter_len != 32 {
 		t.Errorf("filter_len is %d, expected 32", cbf.filter_len)
 	}
-	if len(cbf.counters) != 32 {
-		t.Errorf("counters length is %d, expected 32", len(cbf.counters))
+	if len(cbf.counters) != 16 {
+		t.Errorf("counters length is %d bytes, expected 16 (two 4-bit counters per byte)", len(cbf.counters))
 	}
-	for i, c := range cbf.counters {
-		if c != 0 {
+	for i := range 32 {
+		if c := cbf.counter(i); c != 0 {
 			t.Errorf("counter[%d] is %d, expected 0", i, c)
 		}
 	}
@@ -610,11 +817,11 @@ func TestCountingBloomFilter_Add(t *testing.T) {
 
 	cbf.Add(s)
 
-	if cbf.counters[pos1] != 1 {
-		t.Errorf("counter[%d] is %d, expected 1", pos1, cbf.counters[pos1])
+	if cbf.counter(pos1) != 1 {
+		t.Errorf("counter[%d] is %d, expected 1", pos1, cbf.counter(pos1))
 	}
-	if cbf.counters[pos2] != 1 {
-		t.Errorf("counter[%d] is %d, expected 1", pos2, cbf.counters[pos2])
+	if cbf.counter(pos2) != 1 {
+		t.Errorf("counter[%d] is %d, expected 1", pos2, cbf.counter(pos2))
 	}
 }
 
@@ -627,8 +834,8 @@ func TestCountingBloomFilter_Add_Increments(t *testing.T) {
 	cbf.Add(s)
 	cbf.Add(s)
 
-	if cbf.counters[pos1] != 3 {
-		t.Errorf("counter[%d] is %d, expected 3", pos1, cbf.counters[pos1])
+	if cbf.counter(pos1) != 3 {
+		t.Errorf("counter[%d] is %d, expected 3", pos1, cbf.counter(pos1))
 	}
 }
 
@@ -681,8 +888,8 @@ func TestCountingBloomFilter_Remove_Decrements(t *testing.T) {
 	cbf.Add(s)
 	cbf.Remove(s)
 
-	if cbf.counters[pos1] != 2 {
-		t.Errorf("counter[%d] is %d, expected 2", pos1, cbf.counters[pos1])
+	if cbf.counter(pos1) != 2 {
+		t.Errorf("counter[%d] is %d, expected 2", pos1, cbf.counter(pos1))
 	}
 }
 
@@ -692,15 +899,17 @@ func TestCountingBloomFilter_Remove_NoUnderflow(t *testing.T) {
 	pos1 := cbf.Hash1(s)
 	pos2 := cbf.Hash2(s)
 
-	cbf.Remove(s)
-	cbf.Remove(s)
-	cbf.Remove(s)
+	for range 3 {
+		if err := cbf.Remove(s); !errors.Is(err, ErrNotPresent) {
+			t.Errorf("Remove of never added key returned %v, expected ErrNotPresent", err)
+		}
+	}
 
-	if cbf.counters[pos1] != 0 {
-		t.Errorf("counter[%d] is %d, expected 0 (no underflow)", pos1, cbf.counters[pos1])
+	if cbf.counter(pos1) != 0 {
+		t.Errorf("counter[%d] is %d, expected 0 (no underflow)", pos1, cbf.counter(pos1))
 	}
-	if cbf.counters[pos2] != 0 {
-		t.Errorf("counter[%d] is %d, expected 0 (no underflow)", pos2, cbf.counters[pos2])
+	if cbf.counter(pos2) != 0 {
+		t.Errorf("counter[%d] is %d, expected 0 (no underflow)", pos2, cbf.counter(pos2))
 	}
 }
 
@@ -731,13 +940,13 @@ func TestCountingBloomFilter_CollisionHandling(t *testing.T) {
 
 	if pos1Cat == pos1Dog {
 		t.Logf("'cat' and 'dog' collide at position %d", pos1Cat)
-		if cbf.counters[pos1Cat] != 2 {
-			t.Errorf("counter should be 2 after collision, got %d", cbf.counters[pos1Cat])
+		if cbf.counter(pos1Cat) != 2 {
+			t.Errorf("counter should be 2 after collision, got %d", cbf.counter(pos1Cat))
 		}
 
 		cbf.Remove("cat")
-		if cbf.counters[pos1Cat] != 1 {
-			t.Errorf("counter should be 1 after removing 'cat', got %d", cbf.counters[pos1Cat])
+		if cbf.counter(pos1Cat) != 1 {
+			t.Errorf("counter should be 1 after removing 'cat', got %d", cbf.counter(pos1Cat))
 		}
 		if !cbf.IsValue("dog") {
 			t.Error("'dog' should still be found after removing 'cat' (collision case)")
@@ -754,8 +963,11 @@ func TestCountingBloomFilter_Add_NoOverflow(t *testing.T) {
 		cbf.Add(s)
 	}
 
-	if cbf.counters[pos1] != 255 {
-		t.Errorf("counter should cap at 255, got %d", cbf.counters[pos1])
+	if cbf.counter(pos1) != COUNTER_MAX {
+		t.Errorf("counter should cap at %d, got %d", COUNTER_MAX, cbf.counter(pos1))
+	}
+	if !cbf.Overflowed() {
+		t.Error("Overflowed should be set after a counter reached the cap")
 	}
 }
 
@@ -830,6 +1042,145 @@ func TestCountingBloomFilter_AllTestStrings(t *testing.T) {
 	}
 }
 
+func TestCountingBloomFilter_PackedCountersAreIndependent(t *testing.T) {
+	cbf := NewCountingBloomFilter(33)
+	for pos := range 33 {
+		cbf.setCounter(pos, uint8(pos%16))
+	}
+	for pos := range 33 {
+		if got := cbf.counter(pos); got != uint8(pos%16) {
+			t.Errorf("counter[%d] is %d, expected %d", pos, got, pos%16)
+		}
+	}
+}
+
+func TestCountingBloomFilter_StuckCounterSurvivesRemove(t *testing.T) {
+	cbf := NewCountingBloomFilter(32)
+	s := "overflow_test"
+	pos1 := cbf.Hash1(s)
+
+	for range 20 {
+		cbf.Add(s)
+	}
+	for range 20 {
+		if err := cbf.Remove(s); err != nil {
+			t.Fatalf("Remove: %v", err)
+		}
+	}
+
+	// the real count is unknown once stuck, so Remove must not decrement it
+	if cbf.counter(pos1) != COUNTER_MAX {
+		t.Errorf("stuck counter is %d after removes, expected %d", cbf.counter(pos1), COUNTER_MAX)
+	}
+	if !cbf.IsValue(s) {
+		t.Error("key with stuck counters should still be found")
+	}
+}
+
+func TestCountingBloomFilter_RemoveErrorChangesNothing(t *testing.T) {
+	cbf := NewCountingBloomFilter(1000)
+	cbf.Add("cat")
+
+	before := append([]uint8(nil), cbf.counters...)
+	for _, s := range generateTestStrings() {
+		if cbf.IsValue(s) {
+			continue
+		}
+		if err := cbf.Remove(s); !errors.Is(err, ErrNotPresent) {
+			t.Errorf("Remove(%q) = %v, expected ErrNotPresent", s, err)
+		}
+	}
+
+	for i := range before {
+		if cbf.counters[i] != before[i] {
+			t.Fatal("failed Remove changed counters")
+		}
+	}
+	if !cbf.IsValue("cat") {
+		t.Error("'cat' lost after failed removes of other keys")
+	}
+}
+
+func TestCountingBloomFilter_RemoveSamePositionKey(t *testing.T) {
+	cbf := NewCountingBloomFilter(32)
+
+	key := ""
+	for i := range 1000 {
+		if s := fmt.Sprintf("k%d", i); cbf.Hash1(s) == cbf.Hash2(s) {
+			key = s
+			break
+		}
+	}
+	if key == "" {
+		t.Skip("no key with equal hashes found")
+	}
+
+	other := NewCountingBloomFilter(32)
+	other.setCounter(other.Hash1(key), 1)
+	if err := other.Remove(key); !errors.Is(err, ErrNotPresent) {
+		t.Errorf("Remove with counter 1 at a doubled position = %v, expected ErrNotPresent", err)
+	}
+
+	cbf.Add(key)
+	if err := cbf.Remove(key); err != nil {
+		t.Errorf("Remove(%q): %v", key, err)
+	}
+	if cbf.IsValue(key) {
+		t.Errorf("%q still present after Remove", key)
+	}
+}
+
+func TestCountingBloomFilter_Saturation(t *testing.T) {
+	cbf := NewCountingBloomFilter(32)
+	if cbf.Saturation() != 0 || cbf.Overflowed() {
+		t.Error("empty filter should not be saturated")
+	}
+
+	for range COUNTER_MAX {
+		cbf.Add("hot")
+	}
+	expected := 2.0 / 32
+	if cbf.Hash1("hot") == cbf.Hash2("hot") {
+		expected = 1.0 / 32
+	}
+	if cbf.Saturation() != expected {
+		t.Errorf("Saturation = %v, expected %v", cbf.Saturation(), expected)
+	}
+}
+
+func TestCountingBloomFilter_ToBitFilter(t *testing.T) {
+	cbf := NewCountingBloomFilter(1000)
+	for i := range 100 {
+		cbf.Add(fmt.Sprintf("item_%d", i))
+	}
+	for i := range 50 {
+		cbf.Remove(fmt.Sprintf("item_%d", i))
+	}
+
+	bf := cbf.ToBitFilter()
+	if bf.BitLen() != 1000 || bf.K() != 2 || bf.Scheme() != HashLab {
+		t.Errorf("bit filter m=%d k=%d scheme=%v, expected 1000, 2, lab", bf.BitLen(), bf.K(), bf.Scheme())
+	}
+	for i := range 1000 {
+		s := fmt.Sprintf("item_%d", i)
+		if bf.IsValue(s) != cbf.IsValue(s) {
+			t.Fatalf("IsValue(%q) differs after conversion", s)
+		}
+	}
+
+	// small filters answer like the lab BloomFilter too
+	small := NewCountingBloomFilter(32)
+	lab := NewBloomFilter(32)
+	for _, s := range generateTestStrings()[:3] {
+		small.Add(s)
+		lab.Add(s)
+	}
+	bits := small.ToBitFilter()
+	if uint32(bits.bits[0]) != lab.bitmask {
+		t.Errorf("bit filter %b, lab BloomFilter %b", bits.bits[0], lab.bitmask)
+	}
+}
+
 func BenchmarkCountingBloomFilter_Add(b *testing.B) {
 	cbf := NewCountingBloomFilter(32)
 	testStrings := generateTestStrings()
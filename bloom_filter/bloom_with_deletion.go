@@ -1,18 +1,34 @@
 package bloomfilter
 
+import "errors"
+
 // This is counting bloom filter variant that tries to supports deletion by replacing bits with counters ¯\_(ツ)_/¯
 // but the thing is that remove on false positives will still corrupt the filter and cause false negatives.
 // So... the precondition for using such implementation safely might be informally state like "PLEASE lnly call Remove for elements that you were actually Added!!!"
+// CuckooFilter in cuckoo.go does deletion properly.
+//
+// Counters are 4 bits, two per byte: with a sane load a counter practically never goes above 15
+// (Fan et al. show P(counter >= 16) < 1.37e-15 * m for the optimal k), so 8 bits per counter are mostly zeros.
+// A counter which does reach COUNTER_MAX is stuck there for good: its real value is unknown from then on,
+// and decrementing it could turn into a false negative. The filter remembers that it happened (Overflowed).
+//
+// Remove refuses keys with any counter at zero - such key was surely never added, and decrementing
+// its other counters would take them away from keys which were. It can't catch false positives, though.
+
+const COUNTER_MAX = 15
+
+var ErrNotPresent = errors.New("key is not in the filter")
 
 type CountingBloomFilter struct {
 	filter_len int
-	counters   []uint8
+	counters   []uint8 // packed: counter i is the low nibble of byte i/2 for even i, the high one for odd
+	overflowed bool
 }
 
 func NewCountingBloomFilter(f_len int) *CountingBloomFilter {
 	return &CountingBloomFilter{
 		filter_len: f_len,
-		counters:   make([]uint8, f_len),
+		counters:   make([]uint8, (f_len+1)/2),
 	}
 }
 
@@ -32,32 +48,99 @@ func (cbf *CountingBloomFilter) Hash2(s string) int {
 	return cbf.hasher(s, MAGIC_2)
 }
 
+// Time: O(1)
+// Space: O(1)
+func (cbf *CountingBloomFilter) counter(pos int) uint8 {
+	return cbf.counters[pos/2] >> (4 * (pos % 2)) & 0x0F
+}
+
+// Time: O(1)
+// Space: O(1)
+func (cbf *CountingBloomFilter) setCounter(pos int, value uint8) {
+	shift := 4 * (pos % 2)
+	cbf.counters[pos/2] = cbf.counters[pos/2]&^(0x0F<<shift) | value<<shift
+}
+
+func (cbf *CountingBloomFilter) increment(pos int) {
+	switch c := cbf.counter(pos); {
+	case c == COUNTER_MAX:
+		// stuck
+	case c == COUNTER_MAX-1:
+		cbf.setCounter(pos, COUNTER_MAX)
+		cbf.overflowed = true
+	default:
+		cbf.setCounter(pos, c+1)
+	}
+}
+
+func (cbf *CountingBloomFilter) decrement(pos int) {
+	if c := cbf.counter(pos); c != COUNTER_MAX {
+		cbf.setCounter(pos, c-1)
+	}
+}
+
 func (cbf *CountingBloomFilter) Add(s string) {
 	pos1 := cbf.Hash1(s)
 	pos2 := cbf.Hash2(s)
 
-	if cbf.counters[pos1] < 255 {
-		cbf.counters[pos1]++
-	}
-	if cbf.counters[pos2] < 255 {
-		cbf.counters[pos2]++
-	}
+	cbf.increment(pos1)
+	cbf.increment(pos2)
 }
 
-func (cbf *CountingBloomFilter) Remove(s string) {
+// Remove returns ErrNotPresent and changes nothing if any counter of the key is zero.
+// When both hashes hit the same counter, Add counted the key there twice, so Remove needs 2 to take back.
+func (cbf *CountingBloomFilter) Remove(s string) error {
 	pos1 := cbf.Hash1(s)
 	pos2 := cbf.Hash2(s)
 
-	if cbf.counters[pos1] > 0 {
-		cbf.counters[pos1]--
-	}
-	if cbf.counters[pos2] > 0 {
-		cbf.counters[pos2]--
+	c1 := cbf.counter(pos1)
+	if c1 == 0 || cbf.counter(pos2) == 0 || (pos1 == pos2 && c1 < 2) {
+		return ErrNotPresent
 	}
+
+	cbf.decrement(pos1)
+	cbf.decrement(pos2)
+	return nil
 }
 
 func (cbf *CountingBloomFilter) IsValue(s string) bool {
 	pos1 := cbf.Hash1(s)
 	pos2 := cbf.Hash2(s)
-	return cbf.counters[pos1] > 0 && cbf.counters[pos2] > 0
+	return cbf.counter(pos1) > 0 && cbf.counter(pos2) > 0
+}
+
+// Overflowed reports whether any counter has ever reached COUNTER_MAX (and got stuck there).
+func (cbf *CountingBloomFilter) Overflowed() bool {
+	return cbf.overflowed
+}
+
+// Saturation returns the share of counters stuck at COUNTER_MAX.
+// Anything noticeably above zero means the filter is overloaded, and those positions can never be freed by Remove.
+// Time: O(filter_len)
+// Space: O(1)
+func (cbf *CountingBloomFilter) Saturation() float64 {
+	if cbf.filter_len == 0 {
+		return 0
+	}
+	saturated := 0
+	for pos := range cbf.filter_len {
+		if cbf.counter(pos) == COUNTER_MAX {
+			saturated++
+		}
+	}
+	return float64(saturated) / float64(cbf.filter_len)
+}
+
+// ToBitFilter drops the counts: every non-zero counter becomes a set bit.
+// The result answers IsValue exactly like cbf, in 1 bit per position instead of 4, but can't Remove anymore.
+// Time: O(filter_len)
+// Space: O(filter_len / 64)
+func (cbf *CountingBloomFilter) ToBitFilter() *SizedBloomFilter {
+	bf := newLabSizedBloomFilter(uint64(cbf.filter_len))
+	for pos := range cbf.filter_len {
+		if cbf.counter(pos) > 0 {
+			bf.bits[pos/64] |= 1 << (pos % 64)
+		}
+	}
+	return bf
 }
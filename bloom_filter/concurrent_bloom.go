@@ -0,0 +1,117 @@
+package bloomfilter
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// ConcurrentBloomFilter is SizedBloomFilter for many goroutines at once, without locks.
+//
+// BloomFilter.Add is a read-modify-write of the whole mask: two goroutines adding at the same time
+// can both read the old value and one of the new bits gets lost - a false negative later.
+// Here every bit is set with an atomic OR on its 64-bit word, so concurrent Adds never overwrite each other,
+// and IsValue is just atomic loads.
+//
+// Bits only ever go from 0 to 1, which makes snapshots simple: copying the words one by one with atomic loads
+// gives a state that contains every Add completed before the copy started.
+// Adds running during the copy may be seen partially: the copy can hold one of their words and miss another,
+// so it is not necessarily a state the filter ever was in - it just never loses a completed Add.
+type ConcurrentBloomFilter struct {
+	m    uint64
+	k    int
+	bits []atomic.Uint64
+}
+
+// NewConcurrentBloomFilter builds a filter for n expected items and target false positive rate p.
+// Time: O(m/64)
+// Space: O(m/64)
+func NewConcurrentBloomFilter(n int, p float64) (*ConcurrentBloomFilter, error) {
+	m, k, err := OptimalParams(n, p)
+	if err != nil {
+		return nil, err
+	}
+	return NewConcurrentBloomFilterMK(m, k)
+}
+
+// Time: O(m/64)
+// Space: O(m/64)
+func NewConcurrentBloomFilterMK(m uint64, k int) (*ConcurrentBloomFilter, error) {
+	if m == 0 || k <= 0 || k > MAX_HASHES {
+		return nil, ErrInvalidParams
+	}
+	return &ConcurrentBloomFilter{
+		m:    m,
+		k:    k,
+		bits: make([]atomic.Uint64, (m+63)/64),
+	}, nil
+}
+
+// Time: O(len(s) + k)
+// Space: O(1)
+func (bf *ConcurrentBloomFilter) Add(s string) {
+	h1, h2 := baseHashes(s)
+	for i := range bf.k {
+		pos := (h1 + uint64(i)*h2) % bf.m
+		word := &bf.bits[pos/64]
+		// skip the write when the bit is there already, hot keys then don't bounce the cache line
+		if mask := uint64(1) << (pos % 64); word.Load()&mask == 0 {
+			word.Or(mask)
+		}
+	}
+}
+
+// Time: O(len(s) + k)
+// Space: O(1)
+func (bf *ConcurrentBloomFilter) IsValue(s string) bool {
+	h1, h2 := baseHashes(s)
+	for i := range bf.k {
+		pos := (h1 + uint64(i)*h2) % bf.m
+		if bf.bits[pos/64].Load()&(1<<(pos%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Snapshot copies the current state into a plain SizedBloomFilter (e.g. to serialize it).
+// Positions are computed the same way, so the copy knows every Add completed before Snapshot started;
+// Adds running concurrently may be in it partially (see the type comment).
+// Time: O(m/64)
+// Space: O(m/64)
+func (bf *ConcurrentBloomFilter) Snapshot() *SizedBloomFilter {
+	bits := make([]uint64, len(bf.bits))
+	for i := range bf.bits {
+		bits[i] = bf.bits[i].Load()
+	}
+	return &SizedBloomFilter{m: bf.m, k: bf.k, scheme: HashDouble, bits: bits}
+}
+
+// Merge ORs a snapshot of other into bf. Both filters can keep receiving Adds meanwhile:
+// every Add completed on other before Merge started is in bf afterwards, Adds still running may be merged partially.
+// Time: O(m/64)
+// Space: O(1)
+func (bf *ConcurrentBloomFilter) Merge(other *ConcurrentBloomFilter) error {
+	if bf.m != other.m || bf.k != other.k {
+		return fmt.Errorf("%w: m=%d k=%d and m=%d k=%d", ErrFilterMismatch, bf.m, bf.k, other.m, other.k)
+	}
+	if bf == other {
+		return nil
+	}
+
+	for i := range other.bits {
+		if word := other.bits[i].Load(); word != 0 {
+			bf.bits[i].Or(word)
+		}
+	}
+	return nil
+}
+
+// BitLen returns m, the number of bits in the filter.
+func (bf *ConcurrentBloomFilter) BitLen() uint64 {
+	return bf.m
+}
+
+// K returns the number of hash positions per key.
+func (bf *ConcurrentBloomFilter) K() int {
+	return bf.k
+}
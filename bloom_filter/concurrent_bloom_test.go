@@ -0,0 +1,152 @@
+package bloomfilter
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestConcurrentBloomFilter_MatchesSized(t *testing.T) {
+	cbf, _ := NewConcurrentBloomFilter(1000, 0.01)
+	sbf, _ := NewSizedBloomFilter(1000, 0.01)
+	for i := range 1000 {
+		cbf.Add(fmt.Sprintf("key-%d", i))
+		sbf.Add(fmt.Sprintf("key-%d", i))
+	}
+
+	snap := cbf.Snapshot()
+	for i := range sbf.bits {
+		if snap.bits[i] != sbf.bits[i] {
+			t.Fatalf("word %d differs from SizedBloomFilter", i)
+		}
+	}
+	for i := range 5000 {
+		key := fmt.Sprintf("probe-%d", i)
+		if cbf.IsValue(key) != sbf.IsValue(key) {
+			t.Fatalf("IsValue(%s) differs from SizedBloomFilter", key)
+		}
+	}
+}
+
+func TestConcurrentBloomFilter_ParallelAdds(t *testing.T) {
+	const workers, perWorker = 16, 5000
+	cbf, _ := NewConcurrentBloomFilter(workers*perWorker, 0.01)
+
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range perWorker {
+				key := fmt.Sprintf("w%d-%d", w, i)
+				cbf.Add(key)
+				if !cbf.IsValue(key) {
+					t.Errorf("%s not found right after Add", key)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// a lost bit would be a false negative here
+	for w := range workers {
+		for i := range perWorker {
+			if !cbf.IsValue(fmt.Sprintf("w%d-%d", w, i)) {
+				t.Fatalf("w%d-%d lost by concurrent Adds", w, i)
+			}
+		}
+	}
+}
+
+func TestConcurrentBloomFilter_MergeWhileAdding(t *testing.T) {
+	const n = 20_000
+	dst, _ := NewConcurrentBloomFilter(2*n, 0.01)
+	src, _ := NewConcurrentBloomFilter(2*n, 0.01)
+
+	// everything added before Merge must be in the result
+	for i := range n {
+		src.Add(fmt.Sprintf("before-%d", i))
+	}
+
+	var wg sync.WaitGroup
+	for w := range 4 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := range n / 4 {
+				src.Add(fmt.Sprintf("during-src-%d-%d", w, i))
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := range n / 4 {
+				dst.Add(fmt.Sprintf("during-dst-%d-%d", w, i))
+			}
+		}()
+	}
+	if err := dst.Merge(src); err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+	wg.Wait()
+
+	for i := range n {
+		if !dst.IsValue(fmt.Sprintf("before-%d", i)) {
+			t.Fatalf("before-%d missing after Merge", i)
+		}
+	}
+	for w := range 4 {
+		for i := range n / 4 {
+			if !dst.IsValue(fmt.Sprintf("during-dst-%d-%d", w, i)) {
+				t.Fatalf("during-dst-%d-%d lost while merging", w, i)
+			}
+		}
+	}
+}
+
+func TestConcurrentBloomFilter_MergeMismatch(t *testing.T) {
+	a, _ := NewConcurrentBloomFilterMK(1024, 3)
+	b, _ := NewConcurrentBloomFilterMK(1024, 4)
+	if err := a.Merge(b); !errors.Is(err, ErrFilterMismatch) {
+		t.Errorf("err = %v, expected ErrFilterMismatch", err)
+	}
+	if _, err := NewConcurrentBloomFilterMK(0, 3); !errors.Is(err, ErrInvalidParams) {
+		t.Errorf("err = %v, expected ErrInvalidParams", err)
+	}
+}
+
+func BenchmarkConcurrentBloomFilter_AddParallel(b *testing.B) {
+	cbf, _ := NewConcurrentBloomFilter(1_000_000, 0.01)
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			cbf.Add(keys[i%len(keys)])
+			i++
+		}
+	})
+}
+
+func BenchmarkConcurrentBloomFilter_IsValueParallel(b *testing.B) {
+	cbf, _ := NewConcurrentBloomFilter(1_000_000, 0.01)
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+		cbf.Add(keys[i])
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			cbf.IsValue(keys[i%len(keys)])
+			i++
+		}
+	})
+}
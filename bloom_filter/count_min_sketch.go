@@ -0,0 +1,132 @@
+package bloomfilter
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+var (
+	ErrInvalidSketchParams = errors.New("epsilon and delta must be in (0, 1)")
+	ErrSketchMismatch      = errors.New("sketches have different dimensions")
+)
+
+// CountMinSketch counts approximate frequencies in fixed memory, unlike powersets.Bag which keeps every key
+// ("An Improved Data Stream Summary: The Count-Min Sketch and its Applications", Cormode, Muthukrishnan, 2005).
+//
+// It is a depth x width matrix of counters, every row is a Bloom-like hash of the key into `width` counters.
+// Add increments one counter per row, Estimate takes the minimum over rows.
+// Collisions only add, so the estimate never undercounts, and for a stream of N total increments:
+//
+//	width = ceil(e / epsilon), depth = ceil(ln(1 / delta))
+//	Estimate(x) <= count(x) + epsilon * N    with probability at least 1 - delta
+//
+// Conservative update (Estan & Varghese) increments only the counters that are below the new estimate:
+// the guarantee stays and the overestimation is usually much smaller.
+// Merging conservative sketches is still correct, the sum just loses a bit of that advantage.
+type CountMinSketch struct {
+	width        uint64
+	depth        int
+	counters     []uint64 // row-major, depth rows of width counters
+	total        uint64
+	conservative bool
+}
+
+// NewCountMinSketch builds a sketch with error epsilon*N at probability 1-delta.
+// Time: O(width * depth)
+// Space: O(width * depth)
+func NewCountMinSketch(epsilon, delta float64) (*CountMinSketch, error) {
+	if epsilon <= 0 || epsilon >= 1 || delta <= 0 || delta >= 1 {
+		return nil, ErrInvalidSketchParams
+	}
+
+	width := uint64(math.Ceil(math.E / epsilon))
+	depth := int(math.Ceil(math.Log(1 / delta)))
+	return &CountMinSketch{
+		width:    width,
+		depth:    depth,
+		counters: make([]uint64, width*uint64(depth)),
+	}, nil
+}
+
+// NewConservativeCountMinSketch is NewCountMinSketch in conservative update mode.
+// Time: O(width * depth)
+// Space: O(width * depth)
+func NewConservativeCountMinSketch(epsilon, delta float64) (*CountMinSketch, error) {
+	cms, err := NewCountMinSketch(epsilon, delta)
+	if err != nil {
+		return nil, err
+	}
+	cms.conservative = true
+	return cms, nil
+}
+
+// Row positions come from the same double hashing as SizedBloomFilter: row i uses h1 + i*h2.
+// Time: O(len(key) + depth)
+// Space: O(1)
+func (cms *CountMinSketch) forEachCell(key string, fn func(idx uint64)) {
+	h1, h2 := baseHashes(key)
+	for i := range cms.depth {
+		fn(uint64(i)*cms.width + (h1+uint64(i)*h2)%cms.width)
+	}
+}
+
+// Add counts n more occurrences of key.
+// Time: O(len(key) + depth)
+// Space: O(1)
+func (cms *CountMinSketch) Add(key string, n uint64) {
+	cms.total += n
+
+	if !cms.conservative {
+		cms.forEachCell(key, func(idx uint64) {
+			cms.counters[idx] += n
+		})
+		return
+	}
+
+	target := cms.Estimate(key) + n
+	cms.forEachCell(key, func(idx uint64) {
+		cms.counters[idx] = max(cms.counters[idx], target)
+	})
+}
+
+// Estimate returns the approximate count of key, never below the real one.
+// Time: O(len(key) + depth)
+// Space: O(1)
+func (cms *CountMinSketch) Estimate(key string) uint64 {
+	est := uint64(math.MaxUint64)
+	cms.forEachCell(key, func(idx uint64) {
+		est = min(est, cms.counters[idx])
+	})
+	return est
+}
+
+// Merge adds other into cms, as if cms had seen both streams. Dimensions must match.
+// Time: O(width * depth)
+// Space: O(1)
+func (cms *CountMinSketch) Merge(other *CountMinSketch) error {
+	if cms.width != other.width || cms.depth != other.depth {
+		return fmt.Errorf("%w: %dx%d and %dx%d", ErrSketchMismatch, cms.depth, cms.width, other.depth, other.width)
+	}
+
+	for i, c := range other.counters {
+		cms.counters[i] += c
+	}
+	cms.total += other.total
+	return nil
+}
+
+// Total returns N, the sum of all added counts.
+func (cms *CountMinSketch) Total() uint64 {
+	return cms.total
+}
+
+// Width returns the number of counters per row.
+func (cms *CountMinSketch) Width() uint64 {
+	return cms.width
+}
+
+// Depth returns the number of rows.
+func (cms *CountMinSketch) Depth() int {
+	return cms.depth
+}
@@ -0,0 +1,161 @@
+package bloomfilter
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/m0n0x41d/algopher/powersets"
+)
+
+// zipfStream returns a skewed stream of keys and their exact counts.
+func zipfStream(n int, keys uint64) ([]string, powersets.Bag[string]) {
+	rnd := rand.New(rand.NewPCG(MAGIC_1, MAGIC_2))
+	zipf := rand.NewZipf(rnd, 1.1, 1, keys-1)
+
+	exact := powersets.InitBag[string]()
+	stream := make([]string, n)
+	for i := range stream {
+		stream[i] = fmt.Sprintf("event-%d", zipf.Uint64())
+		exact.Put(stream[i])
+	}
+	return stream, exact
+}
+
+func TestNewCountMinSketch(t *testing.T) {
+	cms, err := NewCountMinSketch(0.001, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cms.Width() != 2719 || cms.Depth() != 5 {
+		t.Errorf("dimensions %dx%d, expected 5x2719", cms.Depth(), cms.Width())
+	}
+
+	for _, p := range [][2]float64{{0, 0.1}, {0.1, 0}, {1, 0.1}, {0.1, 1}} {
+		if _, err := NewCountMinSketch(p[0], p[1]); !errors.Is(err, ErrInvalidSketchParams) {
+			t.Errorf("eps=%v delta=%v: err = %v, expected ErrInvalidSketchParams", p[0], p[1], err)
+		}
+	}
+}
+
+func TestCountMinSketch_ExactWithoutCollisions(t *testing.T) {
+	cms, _ := NewCountMinSketch(0.001, 0.01)
+	cms.Add("a", 3)
+	cms.Add("b", 1)
+	cms.Add("a", 2)
+
+	if got := cms.Estimate("a"); got != 5 {
+		t.Errorf("Estimate(a) = %d, expected 5", got)
+	}
+	if got := cms.Estimate("missing"); got != 0 {
+		t.Errorf("Estimate(missing) = %d, expected 0", got)
+	}
+	if cms.Total() != 6 {
+		t.Errorf("Total = %d, expected 6", cms.Total())
+	}
+}
+
+// checkErrorBounds verifies both guarantees: no undercount ever,
+// and the epsilon*N overcount is exceeded for at most a delta share of keys.
+func checkErrorBounds(t *testing.T, cms *CountMinSketch, exact powersets.Bag[string], epsilon, delta float64) (avgErr float64) {
+	t.Helper()
+
+	bound := uint64(epsilon * float64(cms.Total()))
+	violations, sumErr := 0, uint64(0)
+	freqs := exact.Frequencies()
+	for key, count := range freqs {
+		est := cms.Estimate(key)
+		if est < uint64(count) {
+			t.Fatalf("Estimate(%s) = %d below exact %d", key, est, count)
+		}
+		sumErr += est - uint64(count)
+		if est-uint64(count) > bound {
+			violations++
+		}
+	}
+
+	share := float64(violations) / float64(len(freqs))
+	if share > delta {
+		t.Errorf("%.4f of keys exceed epsilon*N = %d, allowed %.4f", share, bound, delta)
+	}
+	return float64(sumErr) / float64(len(freqs))
+}
+
+func TestCountMinSketch_ErrorBounds(t *testing.T) {
+	const epsilon, delta = 0.001, 0.01
+	stream, exact := zipfStream(200_000, 50_000)
+
+	cms, _ := NewCountMinSketch(epsilon, delta)
+	for _, key := range stream {
+		cms.Add(key, 1)
+	}
+	avg := checkErrorBounds(t, cms, exact, epsilon, delta)
+	t.Logf("%d distinct keys, average overcount %.2f, bound %.0f", exact.UniqueSize(), avg, epsilon*float64(cms.Total()))
+}
+
+func TestCountMinSketch_ConservativeIsTighter(t *testing.T) {
+	const epsilon, delta = 0.001, 0.01
+	stream, exact := zipfStream(200_000, 50_000)
+
+	plain, _ := NewCountMinSketch(epsilon, delta)
+	conservative, _ := NewConservativeCountMinSketch(epsilon, delta)
+	for _, key := range stream {
+		plain.Add(key, 1)
+		conservative.Add(key, 1)
+	}
+
+	plainErr := checkErrorBounds(t, plain, exact, epsilon, delta)
+	conservativeErr := checkErrorBounds(t, conservative, exact, epsilon, delta)
+	t.Logf("average overcount: plain %.2f, conservative %.2f", plainErr, conservativeErr)
+	if conservativeErr >= plainErr {
+		t.Errorf("conservative overcount %.2f not below plain %.2f", conservativeErr, plainErr)
+	}
+}
+
+func TestCountMinSketch_Merge(t *testing.T) {
+	const epsilon, delta = 0.001, 0.01
+	stream, exact := zipfStream(100_000, 10_000)
+
+	left, _ := NewCountMinSketch(epsilon, delta)
+	right, _ := NewCountMinSketch(epsilon, delta)
+	whole, _ := NewCountMinSketch(epsilon, delta)
+	for i, key := range stream {
+		if i%2 == 0 {
+			left.Add(key, 1)
+		} else {
+			right.Add(key, 1)
+		}
+		whole.Add(key, 1)
+	}
+
+	if err := left.Merge(right); err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+	if left.Total() != whole.Total() {
+		t.Errorf("Total = %d, expected %d", left.Total(), whole.Total())
+	}
+	for key := range exact.Frequencies() {
+		if left.Estimate(key) != whole.Estimate(key) {
+			t.Fatalf("merged Estimate(%s) = %d, single sketch %d", key, left.Estimate(key), whole.Estimate(key))
+		}
+	}
+
+	other, _ := NewCountMinSketch(0.01, delta)
+	if err := left.Merge(other); !errors.Is(err, ErrSketchMismatch) {
+		t.Errorf("err = %v, expected ErrSketchMismatch", err)
+	}
+}
+
+func BenchmarkCountMinSketch_Add(b *testing.B) {
+	cms, _ := NewCountMinSketch(0.001, 0.01)
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cms.Add(keys[i%len(keys)], 1)
+	}
+}
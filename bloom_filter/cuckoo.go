@@ -0,0 +1,208 @@
+package bloomfilter
+
+import (
+	"errors"
+	"math/bits"
+	"math/rand/v2"
+)
+
+const (
+	CUCKOO_BUCKET_SIZE = 4
+	CUCKOO_MAX_KICKS   = 500
+)
+
+var ErrFilterFull = errors.New("filter is full")
+
+// CuckooFilter is the deletion-capable alternative to CountingBloomFilter
+// ("Cuckoo Filter: Practically Better Than Bloom", Fan, Andersen, Kaminsky, Mitzenmacher, 2014).
+//
+// Instead of bits it stores a short fingerprint of every key in one of two candidate buckets.
+// Partial-key cuckoo hashing computes the second bucket from the first one and the fingerprint only:
+//
+//	i1 = hash(x)
+//	i2 = i1 XOR hash(fingerprint(x))
+//
+// so a fingerprint moved out of its bucket always knows where its other home is,
+// without the original key. XOR works both ways (i1 = i2 XOR hash(fp)) with power-of-two bucket count.
+//
+// When both buckets are full, a random fingerprint is kicked to its other bucket, and so on,
+// at most CUCKOO_MAX_KICKS times. The fingerprint left homeless after that goes to a one-entry victim stash,
+// so nothing is lost, and Add returns ErrFilterFull while the stash is taken.
+//
+// Remove is safe unlike CountingBloomFilter.Remove: it deletes one stored copy of the key's fingerprint,
+// and for a key that was never added there usually is nothing to delete (Remove reports false).
+// Only a key which is a false positive anyway may take away someone else's fingerprint.
+// The same key added twice is stored twice (up to 2*CUCKOO_BUCKET_SIZE copies) and needs two Removes.
+//
+// 16-bit fingerprints give FP rate about 2*CUCKOO_BUCKET_SIZE / 2^16 ≈ 0.012%, load factor up to ~95%.
+type CuckooFilter struct {
+	buckets [][CUCKOO_BUCKET_SIZE]uint16
+	mask    uint64
+	count   int
+	victim  victimEntry
+	rnd     *rand.Rand
+}
+
+// victimEntry is the fingerprint which did not find a place after max kicks.
+type victimEntry struct {
+	used        bool
+	index       uint64
+	fingerprint uint16
+}
+
+// NewCuckooFilter builds a filter for about capacity keys. The bucket count is rounded up to a power of two.
+// Time: O(capacity)
+// Space: O(capacity), 2 bytes per slot
+func NewCuckooFilter(capacity int) (*CuckooFilter, error) {
+	if capacity <= 0 {
+		return nil, ErrInvalidCapacity
+	}
+
+	// leave ~5% of slots free, inserts get slow close to the full table
+	needed := uint64(capacity*100/95/CUCKOO_BUCKET_SIZE + 1)
+	numBuckets := uint64(1) << bits.Len64(needed-1)
+
+	return &CuckooFilter{
+		buckets: make([][CUCKOO_BUCKET_SIZE]uint16, numBuckets),
+		mask:    numBuckets - 1,
+		// fixed seed keeps filters (and tests) reproducible
+		rnd: rand.New(rand.NewPCG(MAGIC_1, MAGIC_2)),
+	}, nil
+}
+
+// Time: O(len(s))
+// Space: O(1)
+func (cf *CuckooFilter) indexAndFingerprint(s string) (uint64, uint16) {
+	h := mix64(fnv1a64(s, MAGIC_1))
+	// the top bits for the fingerprint, the low ones for the index - they must not overlap
+	fp := uint16(h >> 48)
+	if fp == 0 {
+		fp = 1 // 0 marks an empty slot
+	}
+	return h & cf.mask, fp
+}
+
+// Time: O(1)
+// Space: O(1)
+func (cf *CuckooFilter) altIndex(i uint64, fp uint16) uint64 {
+	return (i ^ mix64(uint64(fp))) & cf.mask
+}
+
+// Add stores the key fingerprint, ErrFilterFull means the key was not added.
+// Time: O(len(s)) on average, O(CUCKOO_MAX_KICKS) worst case
+// Space: O(1)
+func (cf *CuckooFilter) Add(s string) error {
+	if cf.victim.used {
+		return ErrFilterFull
+	}
+
+	i1, fp := cf.indexAndFingerprint(s)
+	i2 := cf.altIndex(i1, fp)
+	if cf.insert(i1, fp) || cf.insert(i2, fp) {
+		cf.count++
+		return nil
+	}
+
+	i := i1
+	if cf.rnd.IntN(2) == 1 {
+		i = i2
+	}
+	for range CUCKOO_MAX_KICKS {
+		slot := cf.rnd.IntN(CUCKOO_BUCKET_SIZE)
+		fp, cf.buckets[i][slot] = cf.buckets[i][slot], fp
+		i = cf.altIndex(i, fp)
+		if cf.insert(i, fp) {
+			cf.count++
+			return nil
+		}
+	}
+
+	// the new key is in the table now, the one left in hand goes to the stash
+	cf.victim = victimEntry{used: true, index: i, fingerprint: fp}
+	cf.count++
+	return nil
+}
+
+// Time: O(CUCKOO_BUCKET_SIZE)
+// Space: O(1)
+func (cf *CuckooFilter) insert(i uint64, fp uint16) bool {
+	for slot, stored := range cf.buckets[i] {
+		if stored == 0 {
+			cf.buckets[i][slot] = fp
+			return true
+		}
+	}
+	return false
+}
+
+// Time: O(len(s))
+// Space: O(1)
+func (cf *CuckooFilter) IsValue(s string) bool {
+	i1, fp := cf.indexAndFingerprint(s)
+	i2 := cf.altIndex(i1, fp)
+
+	if cf.victim.used && cf.victim.fingerprint == fp && (cf.victim.index == i1 || cf.victim.index == i2) {
+		return true
+	}
+	for _, stored := range cf.buckets[i1] {
+		if stored == fp {
+			return true
+		}
+	}
+	for _, stored := range cf.buckets[i2] {
+		if stored == fp {
+			return true
+		}
+	}
+	return false
+}
+
+// Remove deletes one copy of the key fingerprint, reports whether there was one.
+// Time: O(len(s))
+// Space: O(1)
+func (cf *CuckooFilter) Remove(s string) bool {
+	i1, fp := cf.indexAndFingerprint(s)
+	i2 := cf.altIndex(i1, fp)
+
+	if cf.victim.used && cf.victim.fingerprint == fp && (cf.victim.index == i1 || cf.victim.index == i2) {
+		cf.victim = victimEntry{}
+		cf.count--
+		return true
+	}
+	if !cf.delete(i1, fp) && !cf.delete(i2, fp) {
+		return false
+	}
+	cf.count--
+
+	// a slot is free now, try to bring the stashed fingerprint home
+	if cf.victim.used {
+		v := cf.victim
+		cf.victim = victimEntry{}
+		if !cf.insert(v.index, v.fingerprint) && !cf.insert(cf.altIndex(v.index, v.fingerprint), v.fingerprint) {
+			cf.victim = v
+		}
+	}
+	return true
+}
+
+// Time: O(CUCKOO_BUCKET_SIZE)
+// Space: O(1)
+func (cf *CuckooFilter) delete(i uint64, fp uint16) bool {
+	for slot, stored := range cf.buckets[i] {
+		if stored == fp {
+			cf.buckets[i][slot] = 0
+			return true
+		}
+	}
+	return false
+}
+
+// Count returns the number of stored fingerprints.
+func (cf *CuckooFilter) Count() int {
+	return cf.count
+}
+
+// LoadFactor returns the share of occupied slots.
+func (cf *CuckooFilter) LoadFactor() float64 {
+	return float64(cf.count) / float64(len(cf.buckets)*CUCKOO_BUCKET_SIZE)
+}
@@ -0,0 +1,201 @@
+package bloomfilter
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestNewCuckooFilter(t *testing.T) {
+	if _, err := NewCuckooFilter(0); !errors.Is(err, ErrInvalidCapacity) {
+		t.Errorf("capacity 0: err = %v, expected ErrInvalidCapacity", err)
+	}
+
+	cf, _ := NewCuckooFilter(1000)
+	n := len(cf.buckets)
+	if n&(n-1) != 0 || n*CUCKOO_BUCKET_SIZE < 1000 {
+		t.Errorf("%d buckets, expected a power of two holding 1000 keys", n)
+	}
+}
+
+func TestCuckooFilter_AltIndexIsSymmetric(t *testing.T) {
+	cf, _ := NewCuckooFilter(1000)
+	for _, s := range generateTestStrings() {
+		i1, fp := cf.indexAndFingerprint(s)
+		i2 := cf.altIndex(i1, fp)
+		if cf.altIndex(i2, fp) != i1 {
+			t.Errorf("altIndex(altIndex(%d)) != %d for %q", i1, i1, s)
+		}
+	}
+}
+
+func TestCuckooFilter_AddIsValueRemove(t *testing.T) {
+	cf, _ := NewCuckooFilter(100)
+	for _, s := range generateTestStrings() {
+		if err := cf.Add(s); err != nil {
+			t.Fatalf("Add(%q): %v", s, err)
+		}
+	}
+	if cf.Count() != 10 {
+		t.Errorf("Count = %d, expected 10", cf.Count())
+	}
+	for _, s := range generateTestStrings() {
+		if !cf.IsValue(s) {
+			t.Errorf("%q not found after Add", s)
+		}
+	}
+
+	if !cf.Remove("0123456789") {
+		t.Error("Remove of added key returned false")
+	}
+	if cf.IsValue("0123456789") {
+		t.Error("key found after Remove")
+	}
+	if cf.Count() != 9 {
+		t.Errorf("Count = %d, expected 9", cf.Count())
+	}
+	for _, s := range generateTestStrings()[1:] {
+		if !cf.IsValue(s) {
+			t.Errorf("%q lost after removing another key", s)
+		}
+	}
+}
+
+func TestCuckooFilter_RemoveNeverAddedIsSafe(t *testing.T) {
+	cf, _ := NewCuckooFilter(10_000)
+	for i := range 5000 {
+		cf.Add(fmt.Sprintf("member-%d", i))
+	}
+
+	removed := 0
+	for i := range 5000 {
+		if cf.Remove(fmt.Sprintf("stranger-%d", i)) {
+			removed++
+		}
+	}
+	// only false positives can remove something
+	if removed > 5 {
+		t.Errorf("%d strangers removed something, expected ~FP rate", removed)
+	}
+
+	lost := 0
+	for i := range 5000 {
+		if !cf.IsValue(fmt.Sprintf("member-%d", i)) {
+			lost++
+		}
+	}
+	if lost != removed {
+		t.Errorf("%d members lost, expected exactly the %d stolen fingerprints", lost, removed)
+	}
+}
+
+func TestCuckooFilter_DuplicatesNeedTwoRemoves(t *testing.T) {
+	cf, _ := NewCuckooFilter(100)
+	cf.Add("dup")
+	cf.Add("dup")
+
+	cf.Remove("dup")
+	if !cf.IsValue("dup") {
+		t.Error("second copy lost after one Remove")
+	}
+	cf.Remove("dup")
+	if cf.IsValue("dup") {
+		t.Error("key found after removing both copies")
+	}
+	if cf.Remove("dup") {
+		t.Error("Remove of absent key returned true")
+	}
+}
+
+func TestCuckooFilter_ReportsFull(t *testing.T) {
+	cf, _ := NewCuckooFilter(100)
+	slots := len(cf.buckets) * CUCKOO_BUCKET_SIZE
+
+	added := 0
+	var err error
+	for i := 0; i < 2*slots && err == nil; i++ {
+		if err = cf.Add(fmt.Sprintf("key-%d", i)); err == nil {
+			added++
+		}
+	}
+	if !errors.Is(err, ErrFilterFull) {
+		t.Fatalf("err = %v after %d adds, expected ErrFilterFull", err, added)
+	}
+	if cf.LoadFactor() < 0.85 {
+		t.Errorf("full at load factor %.2f, expected above 0.85", cf.LoadFactor())
+	}
+
+	// nothing accepted before the failure was lost, the stash included
+	for i := range added {
+		if !cf.IsValue(fmt.Sprintf("key-%d", i)) {
+			t.Fatalf("key-%d lost", i)
+		}
+	}
+
+	// removes free space, the stashed fingerprint moves back home and Add works again
+	for i := 0; i < added; i += 2 {
+		cf.Remove(fmt.Sprintf("key-%d", i))
+	}
+	if err := cf.Add("fresh"); err != nil {
+		t.Errorf("Add after Removes: %v", err)
+	}
+}
+
+func TestCuckooFilter_FalsePositiveRate(t *testing.T) {
+	cf, _ := NewCuckooFilter(50_000)
+	for i := range 45_000 {
+		if err := cf.Add(fmt.Sprintf("member-%d", i)); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+	}
+
+	const probes = 200_000
+	fp := 0
+	for i := range probes {
+		if cf.IsValue(fmt.Sprintf("outsider-%d", i)) {
+			fp++
+		}
+	}
+
+	// 2b/2^f is for a full table, 1.5x slack is for the sampling noise of ~20 hits
+	rate := float64(fp) / probes
+	bound := 2.0 * CUCKOO_BUCKET_SIZE / (1 << 16)
+	t.Logf("load %.2f, measured FP rate %.5f, bound %.5f", cf.LoadFactor(), rate, bound)
+	if rate > 1.5*bound {
+		t.Errorf("FP rate %.5f above bound %.5f", rate, bound)
+	}
+}
+
+func BenchmarkCuckooFilter_Add(b *testing.B) {
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+	}
+
+	cf, _ := NewCuckooFilter(b.N + 1)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cf.Add(keys[i%len(keys)])
+		if i%len(keys) == len(keys)-1 {
+			b.StopTimer()
+			for _, k := range keys {
+				cf.Remove(k)
+			}
+			b.StartTimer()
+		}
+	}
+}
+
+func BenchmarkCuckooFilter_IsValue(b *testing.B) {
+	cf, _ := NewCuckooFilter(1_000_000)
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+		cf.Add(keys[i])
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cf.IsValue(keys[i%len(keys)])
+	}
+}
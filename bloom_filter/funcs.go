@@ -1,16 +1,131 @@
 package bloomfilter
 
+import (
+	"iter"
+	"math"
+	"math/bits"
+)
+
+// bitFilter is what filter algebra needs from a Bloom filter: its parameters, to refuse mixing
+// incompatible filters, and word-wise access to its bits. BloomFilter and SizedBloomFilter implement it.
+type bitFilter[F any] interface {
+	params() filterHeader
+	clone() F
+	combine(other F, op func(a, b uint64) uint64)
+}
+
 // The probability of false positives increases somewhat exponentially with the number of elements in the merged filters. This check works best with fairly large filters and is worst when there are either a large number of filters (non-empty) or elements.
-func Merge(filters ...*BloomFilter) *BloomFilter {
+// Filters must have the same length, hash scheme and k - OR-ing bits computed differently gives garbage,
+// so a mismatch is an ErrFilterMismatch error instead.
+func Merge[F bitFilter[F]](filters ...F) (F, error) {
+	return combineAll(filters, func(a, b uint64) uint64 { return a | b })
+}
+
+// Intersect ANDs the filters: the result holds every key present in all of them,
+// plus false positives from bits that different keys happened to set in different filters.
+// So it is less accurate than a filter built from the real set intersection - use it as a prefilter.
+func Intersect[F bitFilter[F]](filters ...F) (F, error) {
+	return combineAll(filters, func(a, b uint64) uint64 { return a & b })
+}
+
+// Time: O(len(filters) * m/64)
+// Space: O(m/64)
+func combineAll[F bitFilter[F]](filters []F, op func(a, b uint64) uint64) (F, error) {
+	var zero F
 	if len(filters) == 0 {
-		return nil
+		return zero, nil
+	}
+
+	want := filters[0].params()
+	for _, f := range filters[1:] {
+		if err := f.params().check(want); err != nil {
+			return zero, err
+		}
 	}
 
-	result := NewBloomFilter(filters[0].filter_len)
-	for _, f := range filters {
-		result.bitmask |= f.bitmask
+	result := filters[0].clone()
+	for _, f := range filters[1:] {
+		result.combine(f, op)
+	}
+	return result, nil
+}
+
+// MembershipFilter is anything answering "maybe present / surely absent".
+type MembershipFilter interface {
+	IsValue(s string) bool
+}
+
+// FilterCandidates streams the candidates the filter (probably) contains - that is as close
+// to "restoring the set" as it gets (see below). Every member among the candidates comes out,
+// plus about FP-rate share of non-members.
+// Time: O(number of candidates * cost of IsValue), lazily
+// Space: O(1)
+func FilterCandidates(filter MembershipFilter, candidates iter.Seq[string]) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for s := range candidates {
+			if filter.IsValue(s) && !yield(s) {
+				return
+			}
+		}
+	}
+}
+
+// estimateCount is the Swamidass–Baldi estimate of the number of distinct keys in a filter
+// with m bits, k hashes and x bits set: n ≈ -(m/k) * ln(1 - x/m).
+// A fully set filter says nothing about its size, the estimate is +Inf then.
+// Time: O(1)
+// Space: O(1)
+func estimateCount(m uint64, k int, x int) float64 {
+	if uint64(x) >= m {
+		return math.Inf(1)
+	}
+	return -float64(m) / float64(k) * math.Log(1-float64(x)/float64(m))
+}
+
+// EstimateCount returns the approximate number of distinct keys added, see estimateCount.
+// Time: O(1)
+// Space: O(1)
+func (bf *BloomFilter) EstimateCount() float64 {
+	return estimateCount(uint64(bf.filter_len), 2, bits.OnesCount32(bf.bitmask))
+}
+
+// EstimateCount returns the approximate number of distinct keys added, see estimateCount.
+// Time: O(m/64)
+// Space: O(1)
+func (bf *SizedBloomFilter) EstimateCount() float64 {
+	x := 0
+	for _, word := range bf.bits {
+		x += bits.OnesCount64(word)
+	}
+	return estimateCount(bf.m, bf.k, x)
+}
+
+func (bf *BloomFilter) params() filterHeader {
+	return newHeader(kindBloom, HashLab, 2, uint64(bf.filter_len))
+}
+
+func (bf *BloomFilter) clone() *BloomFilter {
+	return &BloomFilter{filter_len: bf.filter_len, bitmask: bf.bitmask}
+}
+
+func (bf *BloomFilter) combine(other *BloomFilter, op func(a, b uint64) uint64) {
+	bf.bitmask = uint32(op(uint64(bf.bitmask), uint64(other.bitmask)))
+}
+
+func (bf *SizedBloomFilter) params() filterHeader {
+	return newHeader(kindSized, bf.scheme, uint64(bf.k), bf.m)
+}
+
+func (bf *SizedBloomFilter) clone() *SizedBloomFilter {
+	c := *bf
+	c.bits = append([]uint64(nil), bf.bits...)
+	return &c
+}
+
+func (bf *SizedBloomFilter) combine(other *SizedBloomFilter, op func(a, b uint64) uint64) {
+	for i := range bf.bits {
+		bf.bits[i] = op(bf.bits[i], other.bits[i])
 	}
-	return result
 }
 
 // Regarding task 4 (restoring the original set of values added to the filter):
@@ -22,3 +137,4 @@ func Merge(filters ...*BloomFilter) *BloomFilter {
 // it is not possible and not in general - the domain is potentially infinite, and our codomain is just a 32-bit bitmask. So?
 //
 // If we have some set of "candidates" to check whether they were added to the Bloom filter or not, we might still just iterate over these candidates and check the Bloom filter, keeping false positives in mind, but this is nowhere near "restoring."
+// That iteration is FilterCandidates above.
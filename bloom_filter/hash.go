@@ -0,0 +1,45 @@
+package bloomfilter
+
+// The lab hasher above is a polynomial mod filter_len - fine for a 32-bit mask, useless for sizing:
+// its output never exceeds filter_len and similar strings land on neighbouring bits.
+// Everything beyond the lab filters uses a 64-bit FNV-1a salted with the same magic numbers,
+// pushed through a splitmix64 finalizer so that every output bit depends on every input bit.
+
+const (
+	fnvOffset64 = 14695981039346656037
+	fnvPrime64  = 1099511628211
+)
+
+// Time: O(n) where n = len(s)
+// Space: O(1)
+func fnv1a64(s string, salt uint64) uint64 {
+	h := uint64(fnvOffset64) ^ salt
+	for i := 0; i < len(s); i++ {
+		h ^= uint64(s[i])
+		h *= fnvPrime64
+	}
+	return h
+}
+
+// mix64 is the splitmix64 finalizer.
+// Time: O(1)
+// Space: O(1)
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// baseHashes returns the two independent hashes for Kirsch–Mitzenmacher double hashing:
+// g_i(x) = h1(x) + i*h2(x) behaves like k independent hash functions for Bloom filter purposes
+// ("Less Hashing, Same Performance", 2006). h2 is forced odd so it never degenerates to 0.
+// Time: O(n) where n = len(s)
+// Space: O(1)
+func baseHashes(s string) (h1, h2 uint64) {
+	h1 = mix64(fnv1a64(s, MAGIC_1))
+	h2 = mix64(fnv1a64(s, MAGIC_2)) | 1
+	return h1, h2
+}
@@ -0,0 +1,217 @@
+package bloomfilter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/m0n0x41d/algopher/powersets"
+)
+
+// userStream returns n random user ids (with repeats) and the exact set of distinct ones.
+func userStream(n, users int, seed uint64) ([]string, powersets.PowerSet[string]) {
+	rnd := rand.New(rand.NewPCG(seed, MAGIC_2))
+	exact := powersets.Init[string]()
+	stream := make([]string, n)
+	for i := range stream {
+		stream[i] = fmt.Sprintf("user-%d", rnd.IntN(users))
+		exact.Put(stream[i])
+	}
+	return stream, exact
+}
+
+func relativeError(estimate uint64, exact int) float64 {
+	return math.Abs(float64(estimate)-float64(exact)) / float64(exact)
+}
+
+func TestNewHyperLogLog_InvalidPrecision(t *testing.T) {
+	for _, p := range []int{HLL_MIN_PRECISION - 1, HLL_MAX_PRECISION + 1} {
+		if _, err := NewHyperLogLog(p); !errors.Is(err, ErrInvalidPrecision) {
+			t.Errorf("precision %d: err = %v, expected ErrInvalidPrecision", p, err)
+		}
+	}
+}
+
+func TestHyperLogLog_Empty(t *testing.T) {
+	h, _ := NewHyperLogLog(12)
+	if h.Count() != 0 {
+		t.Errorf("Count = %d, expected 0", h.Count())
+	}
+}
+
+func TestHyperLogLog_AccuracyAgainstPowerSet(t *testing.T) {
+	for _, precision := range []int{10, 12, 14} {
+		for _, users := range []int{100, 5000, 200_000} {
+			t.Run(fmt.Sprintf("p%d/users%d", precision, users), func(t *testing.T) {
+				stream, exact := userStream(2*users, users, uint64(users))
+				h, _ := NewHyperLogLog(precision)
+				for _, id := range stream {
+					h.Add(id)
+				}
+
+				// 3 standard errors, almost never exceeded with a fixed seed
+				stdErr := 1.04 / math.Sqrt(float64(uint64(1)<<precision))
+				got := relativeError(h.Count(), exact.Size())
+				if got > 3*stdErr {
+					t.Errorf("estimate %d, exact %d: error %.4f above %.4f", h.Count(), exact.Size(), got, 3*stdErr)
+				}
+			})
+		}
+	}
+}
+
+func TestHyperLogLog_SparseToDense(t *testing.T) {
+	h, _ := NewHyperLogLog(12)
+	for i := range 100 {
+		h.Add(fmt.Sprintf("user-%d", i))
+	}
+	if !h.IsSparse() {
+		t.Fatal("100 users should fit into the sparse list")
+	}
+	if got := relativeError(h.Count(), 100); got > 0.02 {
+		t.Errorf("sparse estimate %d for 100 users", h.Count())
+	}
+
+	for i := 100; i < 10_000; i++ {
+		h.Add(fmt.Sprintf("user-%d", i))
+	}
+	if h.IsSparse() {
+		t.Error("10000 users should switch to dense registers")
+	}
+}
+
+func TestHyperLogLog_SparseAndDenseAgree(t *testing.T) {
+	stream, _ := userStream(1000, 500, 1)
+	sparse, _ := NewHyperLogLog(12)
+	dense, _ := NewHyperLogLog(12)
+	dense.toDense()
+	for _, id := range stream {
+		sparse.Add(id)
+		dense.Add(id)
+	}
+	if sparse.Count() != dense.Count() {
+		t.Errorf("sparse Count %d, dense Count %d", sparse.Count(), dense.Count())
+	}
+}
+
+func TestHyperLogLog_Merge(t *testing.T) {
+	streamA, exactA := userStream(60_000, 40_000, 1)
+	streamB, exactB := userStream(60_000, 40_000, 2)
+	union := exactA.Union(exactB)
+
+	a, _ := NewHyperLogLog(14)
+	b, _ := NewHyperLogLog(14)
+	whole, _ := NewHyperLogLog(14)
+	for _, id := range streamA {
+		a.Add(id)
+		whole.Add(id)
+	}
+	for _, id := range streamB {
+		b.Add(id)
+		whole.Add(id)
+	}
+
+	if err := a.Merge(b); err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+	if a.Count() != whole.Count() {
+		t.Errorf("merged Count %d, single estimator %d", a.Count(), whole.Count())
+	}
+	if got := relativeError(a.Count(), union.Size()); got > 0.03 {
+		t.Errorf("merged estimate %d, exact union %d", a.Count(), union.Size())
+	}
+
+	small, _ := NewHyperLogLog(10)
+	if err := a.Merge(small); !errors.Is(err, ErrSketchMismatch) {
+		t.Errorf("err = %v, expected ErrSketchMismatch", err)
+	}
+}
+
+func TestHyperLogLog_MergeSparseIntoDense(t *testing.T) {
+	dense, _ := NewHyperLogLog(10)
+	sparse, _ := NewHyperLogLog(10)
+	for i := range 5000 {
+		dense.Add(fmt.Sprintf("a-%d", i))
+	}
+	for i := range 20 {
+		sparse.Add(fmt.Sprintf("b-%d", i))
+	}
+
+	left, right := *dense, *sparse
+	left.registers = bytes.Clone(dense.registers)
+	right.sparse = append([]uint32(nil), sparse.sparse...)
+
+	if err := left.Merge(sparse); err != nil {
+		t.Fatal(err)
+	}
+	if err := right.Merge(dense); err != nil {
+		t.Fatal(err)
+	}
+	if left.Count() != right.Count() {
+		t.Errorf("merge is not symmetric: %d vs %d", left.Count(), right.Count())
+	}
+}
+
+func TestHyperLogLog_MarshalRoundTrip(t *testing.T) {
+	for _, n := range []int{50, 50_000} {
+		h, _ := NewHyperLogLog(12)
+		for i := range n {
+			h.Add(fmt.Sprintf("user-%d", i))
+		}
+
+		var buf bytes.Buffer
+		if _, err := h.WriteTo(&buf); err != nil {
+			t.Fatal(err)
+		}
+		var loaded HyperLogLog
+		if _, err := loaded.ReadFrom(&buf); err != nil {
+			t.Fatalf("n=%d: ReadFrom: %v", n, err)
+		}
+		if loaded.Count() != h.Count() || loaded.IsSparse() != h.IsSparse() {
+			t.Errorf("n=%d: loaded Count %d sparse %v, expected %d %v", n, loaded.Count(), loaded.IsSparse(), h.Count(), h.IsSparse())
+		}
+	}
+}
+
+func TestHyperLogLog_UnmarshalMismatch(t *testing.T) {
+	h, _ := NewHyperLogLog(12)
+	h.Add("x")
+	data, _ := h.MarshalBinary()
+
+	other, _ := NewHyperLogLog(14)
+	if err := other.UnmarshalBinary(data); !errors.Is(err, ErrFilterMismatch) {
+		t.Errorf("other precision: err = %v, expected ErrFilterMismatch", err)
+	}
+	if err := new(BloomFilter).UnmarshalBinary(data); !errors.Is(err, ErrFilterMismatch) {
+		t.Errorf("into BloomFilter: err = %v, expected ErrFilterMismatch", err)
+	}
+}
+
+func TestHyperLogLog_UnmarshalDuplicateIndex(t *testing.T) {
+	// two sparse entries for register 5: ranks 1 and 2
+	payload := append([]byte{hllSparse}, binary.AppendUvarint(nil, 2)...)
+	payload = binary.AppendUvarint(payload, 5<<8|1)
+	payload = binary.AppendUvarint(payload, 1)
+	data := encodeFilter(newHeader(kindHyperLogLog, HashSingle, 0, 12), payload)
+
+	if err := new(HyperLogLog).UnmarshalBinary(data); !errors.Is(err, ErrFilterCorrupted) {
+		t.Errorf("err = %v, expected ErrFilterCorrupted", err)
+	}
+}
+
+func BenchmarkHyperLogLog_Add(b *testing.B) {
+	h, _ := NewHyperLogLog(14)
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h.Add(keys[i%len(keys)])
+	}
+}
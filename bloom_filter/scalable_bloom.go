@@ -0,0 +1,151 @@
+package bloomfilter
+
+import (
+	"errors"
+	"math"
+)
+
+// Almeida et al. recommend growth 2 (slow growth, fewer bits) or 4 (fast growth, fewer layers)
+// and tightening ratio 0.8-0.9.
+const (
+	DEFAULT_GROWTH     = 2
+	DEFAULT_TIGHTENING = 0.85
+)
+
+var ErrInvalidGrowth = errors.New("growth must be at least 2 and tightening ratio in (0, 1)")
+
+// ScalableBloomFilter grows when the cardinality is unknown upfront
+// ("Scalable Bloom Filters", Almeida, Baquero, Preguiça, Hutchison, 2007).
+//
+// It is a chain of SizedBloomFilter layers. When the newest layer is full (holds its planned number of items),
+// a new one is added with `growth` times more capacity and a `tightening` times smaller error rate:
+//
+//	layer i: capacity n0 * growth^i, error p0 * tightening^i, where p0 = p * (1 - tightening)
+//
+// The false positive rate of the whole chain is at most sum(p0 * r^i) = p0 / (1 - r) = p,
+// no matter how many layers there are - that is why the error of every next layer has to shrink.
+//
+// Add writes only to the newest layer (old ones are full, more bits would push them above their error),
+// IsValue has to check all of them.
+type ScalableBloomFilter struct {
+	layers     []*SizedBloomFilter
+	capacities []int
+	count      int // items in the newest layer
+	total      int
+	p0         float64
+	growth     int
+	tightening float64
+}
+
+// NewScalableBloomFilter builds a filter starting with initialCapacity items at overall false positive rate p,
+// with DEFAULT_GROWTH and DEFAULT_TIGHTENING.
+// Time: O(m0/64)
+// Space: O(m0/64) initially
+func NewScalableBloomFilter(initialCapacity int, p float64) (*ScalableBloomFilter, error) {
+	return NewScalableBloomFilterGrowth(initialCapacity, p, DEFAULT_GROWTH, DEFAULT_TIGHTENING)
+}
+
+// Time: O(m0/64)
+// Space: O(m0/64) initially
+func NewScalableBloomFilterGrowth(initialCapacity int, p float64, growth int, tightening float64) (*ScalableBloomFilter, error) {
+	if initialCapacity <= 0 {
+		return nil, ErrInvalidCapacity
+	}
+	if p <= 0 || p >= 1 {
+		return nil, ErrInvalidFPRate
+	}
+	if growth < 2 || tightening <= 0 || tightening >= 1 {
+		return nil, ErrInvalidGrowth
+	}
+
+	sbf := &ScalableBloomFilter{
+		p0:         p * (1 - tightening),
+		growth:     growth,
+		tightening: tightening,
+	}
+	if err := sbf.addLayer(initialCapacity, sbf.p0); err != nil {
+		return nil, err
+	}
+	return sbf, nil
+}
+
+// Time: O(m/64) of the new layer
+// Space: O(m/64) of the new layer
+func (sbf *ScalableBloomFilter) addLayer(capacity int, p float64) error {
+	layer, err := NewSizedBloomFilter(capacity, p)
+	if err != nil {
+		return err
+	}
+	sbf.layers = append(sbf.layers, layer)
+	sbf.capacities = append(sbf.capacities, capacity)
+	sbf.count = 0
+	return nil
+}
+
+// Add skips keys which are (probably) present already: re-adding them would only fill the newest layer
+// with duplicates and make the chain grow for nothing.
+// Time: O(L * (len(s) + k)), amortized O(m/64) for a new layer
+// Space: O(1), new layer from time to time
+func (sbf *ScalableBloomFilter) Add(s string) {
+	if sbf.IsValue(s) {
+		return
+	}
+
+	last := len(sbf.layers) - 1
+	if sbf.count >= sbf.capacities[last] {
+		capacity := sbf.capacities[last] * sbf.growth
+		p := sbf.p0 * math.Pow(sbf.tightening, float64(len(sbf.layers)))
+		// if the layer can't be built (p underflowed after absurdly many layers),
+		// the key still goes into the last one - better a worse FP rate than a false negative
+		if err := sbf.addLayer(capacity, p); err == nil {
+			last++
+		}
+	}
+
+	sbf.layers[last].Add(s)
+	sbf.count++
+	sbf.total++
+}
+
+// Time: O(L * (len(s) + k)) where L = number of layers
+// Space: O(1)
+func (sbf *ScalableBloomFilter) IsValue(s string) bool {
+	// newest layers hold most of the items, start from them
+	for i := len(sbf.layers) - 1; i >= 0; i-- {
+		if sbf.layers[i].IsValue(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Count returns the number of distinct items added (up to false positives of Add's presence check).
+func (sbf *ScalableBloomFilter) Count() int {
+	return sbf.total
+}
+
+// Layers returns the number of sub-filters in the chain.
+func (sbf *ScalableBloomFilter) Layers() int {
+	return len(sbf.layers)
+}
+
+// BitLen returns the total number of bits in all layers.
+func (sbf *ScalableBloomFilter) BitLen() uint64 {
+	var m uint64
+	for _, layer := range sbf.layers {
+		m += layer.BitLen()
+	}
+	return m
+}
+
+// FPRate returns the false positive rate bound of the current chain: 1 - prod(1 - p_i).
+// It stays below the p the filter was created with.
+// Time: O(L)
+// Space: O(1)
+func (sbf *ScalableBloomFilter) FPRate() float64 {
+	pass := 1.0
+	for i := range sbf.layers {
+		pass *= 1 - sbf.p0*math.Pow(sbf.tightening, float64(i))
+	}
+	return 1 - pass
+}
@@ -0,0 +1,107 @@
+package bloomfilter
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestNewScalableBloomFilter_Invalid(t *testing.T) {
+	if _, err := NewScalableBloomFilter(0, 0.01); !errors.Is(err, ErrInvalidCapacity) {
+		t.Errorf("capacity 0: err = %v, expected ErrInvalidCapacity", err)
+	}
+	if _, err := NewScalableBloomFilter(100, 1); !errors.Is(err, ErrInvalidFPRate) {
+		t.Errorf("p = 1: err = %v, expected ErrInvalidFPRate", err)
+	}
+	if _, err := NewScalableBloomFilterGrowth(100, 0.01, 1, 0.9); !errors.Is(err, ErrInvalidGrowth) {
+		t.Errorf("growth 1: err = %v, expected ErrInvalidGrowth", err)
+	}
+	if _, err := NewScalableBloomFilterGrowth(100, 0.01, 2, 1); !errors.Is(err, ErrInvalidGrowth) {
+		t.Errorf("tightening 1: err = %v, expected ErrInvalidGrowth", err)
+	}
+}
+
+func TestScalableBloomFilter_GrowsLayers(t *testing.T) {
+	sbf, _ := NewScalableBloomFilter(100, 0.01)
+	if sbf.Layers() != 1 {
+		t.Fatalf("Layers = %d, expected 1", sbf.Layers())
+	}
+
+	for i := range 2000 {
+		sbf.Add(fmt.Sprintf("key-%d", i))
+	}
+
+	// layers hold 100, 200, 400, 800, ... items; Count may be a bit below 2000,
+	// Add skips keys which are false positives of the existing layers
+	expected, planned := 0, 0
+	for capacity := 100; planned < sbf.Count(); capacity *= 2 {
+		planned += capacity
+		expected++
+	}
+	if sbf.Layers() != expected {
+		t.Errorf("Layers = %d for %d items, expected %d", sbf.Layers(), sbf.Count(), expected)
+	}
+	if sbf.Count() < 1980 {
+		t.Errorf("Count = %d, too many keys were taken for duplicates", sbf.Count())
+	}
+}
+
+func TestScalableBloomFilter_AddWritesOnlyNewestLayer(t *testing.T) {
+	sbf, _ := NewScalableBloomFilter(10, 0.01)
+	for i := range 10 {
+		sbf.Add(fmt.Sprintf("key-%d", i))
+	}
+	first := append([]uint64(nil), sbf.layers[0].bits...)
+
+	for i := 10; i < 30; i++ {
+		sbf.Add(fmt.Sprintf("key-%d", i))
+	}
+	for i, word := range sbf.layers[0].bits {
+		if word != first[i] {
+			t.Fatal("full layer was modified by Add")
+		}
+	}
+}
+
+func TestScalableBloomFilter_DuplicatesNotCounted(t *testing.T) {
+	sbf, _ := NewScalableBloomFilter(10, 0.01)
+	for range 100 {
+		sbf.Add("same")
+	}
+	if sbf.Count() != 1 || sbf.Layers() != 1 {
+		t.Errorf("Count = %d, Layers = %d, expected 1, 1", sbf.Count(), sbf.Layers())
+	}
+}
+
+func TestScalableBloomFilter_FalsePositiveBoundHolds(t *testing.T) {
+	const p = 0.01
+	sbf, _ := NewScalableBloomFilter(1000, p)
+
+	// 50x more than planned initially
+	const n = 50_000
+	for i := range n {
+		sbf.Add(fmt.Sprintf("member-%d", i))
+	}
+	for i := range n {
+		if !sbf.IsValue(fmt.Sprintf("member-%d", i)) {
+			t.Fatalf("member-%d not found", i)
+		}
+	}
+
+	const probes = 100_000
+	fp := 0
+	for i := range probes {
+		if sbf.IsValue(fmt.Sprintf("outsider-%d", i)) {
+			fp++
+		}
+	}
+
+	rate := float64(fp) / probes
+	t.Logf("layers=%d bits=%d measured FP %.4f, bound %.4f", sbf.Layers(), sbf.BitLen(), rate, sbf.FPRate())
+	if sbf.FPRate() > p {
+		t.Errorf("FP bound %.4f exceeds target %.4f", sbf.FPRate(), p)
+	}
+	if rate > p {
+		t.Errorf("measured FP rate %.4f exceeds target %.4f", rate, p)
+	}
+}
@@ -0,0 +1,388 @@
+package bloomfilter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// Serialized filter format (integers are uvarints unless said otherwise):
+//
+//	magic     "BLMF"
+//	version   1 byte; 2 since CountingBloomFilter packs 4-bit counters, version 1 data is still readable
+//	kind      1 byte, which filter type wrote it
+//	scheme    1 byte, HashScheme used to compute positions
+//	k         number of hash positions per key
+//	length    filter_len for lab filters, m (bits) for SizedBloomFilter, precision for HyperLogLog,
+//	          number of fingerprints for static filters
+//	payload   payload length + bytes, layout depends on kind
+//	checksum  CRC-32 (IEEE) of everything above, 4 bytes big endian
+//
+// A filter is just bits, it can't tell that it was built with other parameters - it silently answers wrong.
+// So the header is checked against the receiver: loading into a filter with another kind, length,
+// scheme or k fails with ErrFilterMismatch. A zero-value receiver (var bf BloomFilter) takes the parameters from data.
+
+const (
+	filterMagic   = "BLMF"
+	filterVersion = 2
+)
+
+var (
+	ErrFilterCorrupted = errors.New("serialized filter is corrupted")
+	ErrFilterVersion   = errors.New("unsupported serialized filter version")
+	ErrFilterMismatch  = errors.New("serialized filter parameters do not match")
+)
+
+// HashScheme tells how key positions are computed, filters with different schemes are incompatible.
+type HashScheme uint8
+
+const (
+	// HashLab is the lab polynomial hasher with MAGIC_1 and MAGIC_2, k is always 2.
+	HashLab HashScheme = iota + 1
+	// HashDouble is Kirsch–Mitzenmacher double hashing over salted 64-bit FNV-1a, see baseHashes.
+	HashDouble
+	// HashSingle is one salted 64-bit FNV-1a with the mix64 finalizer per key.
+	HashSingle
+)
+
+func (s HashScheme) String() string {
+	switch s {
+	case HashLab:
+		return "lab"
+	case HashDouble:
+		return "double"
+	case HashSingle:
+		return "single"
+	default:
+		return fmt.Sprintf("HashScheme(%d)", uint8(s))
+	}
+}
+
+type filterKind uint8
+
+const (
+	kindBloom filterKind = iota + 1
+	kindCounting
+	kindSized
+	kindHyperLogLog
+	kindXor
+	kindBinaryFuse
+)
+
+func (k filterKind) String() string {
+	switch k {
+	case kindBloom:
+		return "BloomFilter"
+	case kindCounting:
+		return "CountingBloomFilter"
+	case kindSized:
+		return "SizedBloomFilter"
+	case kindHyperLogLog:
+		return "HyperLogLog"
+	case kindXor:
+		return "XorFilter"
+	case kindBinaryFuse:
+		return "BinaryFuse8"
+	default:
+		return fmt.Sprintf("filterKind(%d)", uint8(k))
+	}
+}
+
+type filterHeader struct {
+	version byte
+	kind    filterKind
+	scheme  HashScheme
+	k       uint64
+	length  uint64
+}
+
+// check compares the header with what the receiver expects.
+// Zero scheme, k or length in `want` mean the receiver is a zero value and accepts any.
+func (h filterHeader) check(want filterHeader) error {
+	switch {
+	case h.kind != want.kind:
+		return fmt.Errorf("%w: data holds %v, loading into %v", ErrFilterMismatch, h.kind, want.kind)
+	case want.scheme != 0 && h.scheme != want.scheme:
+		return fmt.Errorf("%w: hash scheme %v, expected %v", ErrFilterMismatch, h.scheme, want.scheme)
+	case want.k != 0 && h.k != want.k:
+		return fmt.Errorf("%w: k = %d, expected %d", ErrFilterMismatch, h.k, want.k)
+	case want.length != 0 && h.length != want.length:
+		return fmt.Errorf("%w: length %d, expected %d", ErrFilterMismatch, h.length, want.length)
+	}
+	return nil
+}
+
+// newHeader makes a header for encoding or checking, the version is always the current one.
+func newHeader(kind filterKind, scheme HashScheme, k, length uint64) filterHeader {
+	return filterHeader{version: filterVersion, kind: kind, scheme: scheme, k: k, length: length}
+}
+
+// Time: O(len(payload))
+// Space: O(len(payload))
+func encodeFilter(h filterHeader, payload []byte) []byte {
+	buf := make([]byte, 0, len(filterMagic)+3+3*binary.MaxVarintLen64+len(payload)+4)
+	buf = append(buf, filterMagic...)
+	buf = append(buf, filterVersion, byte(h.kind), byte(h.scheme))
+	buf = binary.AppendUvarint(buf, h.k)
+	buf = binary.AppendUvarint(buf, h.length)
+	buf = binary.AppendUvarint(buf, uint64(len(payload)))
+	buf = append(buf, payload...)
+	return binary.BigEndian.AppendUint32(buf, crc32.ChecksumIEEE(buf))
+}
+
+// Time: O(len(data))
+// Space: O(1), payload points into data
+func decodeFilter(data []byte) (filterHeader, []byte, error) {
+	var h filterHeader
+	if len(data) < len(filterMagic)+3+4 || string(data[:len(filterMagic)]) != filterMagic {
+		return h, nil, ErrFilterCorrupted
+	}
+
+	body, sum := data[:len(data)-4], binary.BigEndian.Uint32(data[len(data)-4:])
+	if crc32.ChecksumIEEE(body) != sum {
+		return h, nil, fmt.Errorf("%w: checksum mismatch", ErrFilterCorrupted)
+	}
+
+	body = body[len(filterMagic):]
+	if version := body[0]; version < 1 || version > filterVersion {
+		return h, nil, fmt.Errorf("%w: %d", ErrFilterVersion, version)
+	}
+	h.version, h.kind, h.scheme = body[0], filterKind(body[1]), HashScheme(body[2])
+	body = body[3:]
+
+	var fields [3]uint64
+	for i := range fields {
+		v, n := binary.Uvarint(body)
+		if n <= 0 {
+			return h, nil, ErrFilterCorrupted
+		}
+		fields[i], body = v, body[n:]
+	}
+	h.k, h.length = fields[0], fields[1]
+	if fields[2] != uint64(len(body)) {
+		return h, nil, ErrFilterCorrupted
+	}
+	return h, body, nil
+}
+
+// readFilter reads exactly one serialized filter from r, not a byte more,
+// so several filters can be stored back to back in one stream.
+// The buffer grows with the data actually read, a corrupted payload length can't make it allocate gigabytes upfront.
+// Time: O(size)
+// Space: O(size)
+func readFilter(r io.Reader) ([]byte, int64, error) {
+	var buf bytes.Buffer
+	br := &recordingReader{r: r, buf: &buf}
+
+	if _, err := io.CopyN(&buf, r, int64(len(filterMagic))+3); err != nil {
+		return nil, int64(buf.Len()), noEOF(err)
+	}
+	// k, length, payload length - only the last one matters here
+	var payloadLen uint64
+	for range 3 {
+		v, err := binary.ReadUvarint(br)
+		if err != nil {
+			return nil, int64(buf.Len()), noEOF(err)
+		}
+		payloadLen = v
+	}
+	if _, err := io.CopyN(&buf, r, int64(min(payloadLen, 1<<62))+4); err != nil {
+		return nil, int64(buf.Len()), noEOF(err)
+	}
+	return buf.Bytes(), int64(buf.Len()), nil
+}
+
+// noEOF turns EOF in the middle of a filter into ErrUnexpectedEOF.
+func noEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// recordingReader is an io.ByteReader for binary.ReadUvarint that keeps every byte it read.
+type recordingReader struct {
+	r   io.Reader
+	buf *bytes.Buffer
+}
+
+func (b *recordingReader) ReadByte() (byte, error) {
+	var one [1]byte
+	if _, err := io.ReadFull(b.r, one[:]); err != nil {
+		return 0, err
+	}
+	b.buf.WriteByte(one[0])
+	return one[0], nil
+}
+
+// BloomFilter
+
+// Time: O(1)
+// Space: O(1)
+func (bf *BloomFilter) MarshalBinary() ([]byte, error) {
+	payload := binary.BigEndian.AppendUint32(nil, bf.bitmask)
+	return encodeFilter(newHeader(kindBloom, HashLab, 2, uint64(bf.filter_len)), payload), nil
+}
+
+// Time: O(1)
+// Space: O(1)
+func (bf *BloomFilter) UnmarshalBinary(data []byte) error {
+	h, payload, err := decodeFilter(data)
+	if err != nil {
+		return err
+	}
+	if err := h.check(newHeader(kindBloom, HashLab, 2, uint64(bf.filter_len))); err != nil {
+		return err
+	}
+	if h.length == 0 || len(payload) != 4 {
+		return ErrFilterCorrupted
+	}
+
+	bf.filter_len = int(h.length)
+	bf.bitmask = binary.BigEndian.Uint32(payload)
+	return nil
+}
+
+func (bf *BloomFilter) WriteTo(w io.Writer) (int64, error) {
+	return writeFilter(w, bf)
+}
+
+func (bf *BloomFilter) ReadFrom(r io.Reader) (int64, error) {
+	return readFilterInto(r, bf)
+}
+
+// CountingBloomFilter payload: overflow flag byte, then packed 4-bit counters.
+// Version 1 had one byte per counter and no flag, those are packed on load (anything above 14 becomes stuck).
+
+// Time: O(filter_len)
+// Space: O(filter_len)
+func (cbf *CountingBloomFilter) MarshalBinary() ([]byte, error) {
+	payload := make([]byte, 1, 1+len(cbf.counters))
+	if cbf.overflowed {
+		payload[0] = 1
+	}
+	payload = append(payload, cbf.counters...)
+	return encodeFilter(newHeader(kindCounting, HashLab, 2, uint64(cbf.filter_len)), payload), nil
+}
+
+// Time: O(filter_len)
+// Space: O(filter_len)
+func (cbf *CountingBloomFilter) UnmarshalBinary(data []byte) error {
+	h, payload, err := decodeFilter(data)
+	if err != nil {
+		return err
+	}
+	if err := h.check(newHeader(kindCounting, HashLab, 2, uint64(cbf.filter_len))); err != nil {
+		return err
+	}
+	if h.length == 0 {
+		return ErrFilterCorrupted
+	}
+
+	// sizes are checked before allocating, the length comes from untrusted data
+	if h.version == 1 {
+		if uint64(len(payload)) != h.length {
+			return ErrFilterCorrupted
+		}
+	} else if uint64(len(payload)) != 1+h.length/2+h.length%2 || payload[0] > 1 {
+		return ErrFilterCorrupted
+	}
+
+	loaded := NewCountingBloomFilter(int(h.length))
+	if h.version == 1 {
+		for pos, c := range payload {
+			loaded.setCounter(pos, min(c, COUNTER_MAX))
+			loaded.overflowed = loaded.overflowed || c >= COUNTER_MAX
+		}
+	} else {
+		loaded.overflowed = payload[0] == 1
+		copy(loaded.counters, payload[1:])
+	}
+
+	*cbf = *loaded
+	return nil
+}
+
+func (cbf *CountingBloomFilter) WriteTo(w io.Writer) (int64, error) {
+	return writeFilter(w, cbf)
+}
+
+func (cbf *CountingBloomFilter) ReadFrom(r io.Reader) (int64, error) {
+	return readFilterInto(r, cbf)
+}
+
+// SizedBloomFilter
+
+// Time: O(m/64)
+// Space: O(m/64)
+func (bf *SizedBloomFilter) MarshalBinary() ([]byte, error) {
+	payload := make([]byte, 0, 8*len(bf.bits))
+	for _, word := range bf.bits {
+		payload = binary.LittleEndian.AppendUint64(payload, word)
+	}
+	return encodeFilter(newHeader(kindSized, bf.scheme, uint64(bf.k), bf.m), payload), nil
+}
+
+// Time: O(m/64)
+// Space: O(m/64)
+func (bf *SizedBloomFilter) UnmarshalBinary(data []byte) error {
+	h, payload, err := decodeFilter(data)
+	if err != nil {
+		return err
+	}
+	if err := h.check(newHeader(kindSized, bf.scheme, uint64(bf.k), bf.m)); err != nil {
+		return err
+	}
+	validScheme := h.scheme == HashDouble || (h.scheme == HashLab && h.k == 2)
+	if !validScheme || h.length == 0 || h.k == 0 || h.k > MAX_HASHES {
+		return ErrFilterCorrupted
+	}
+	// (h.length+63)/64 would overflow for lengths near 2^64
+	words := h.length / 64
+	if h.length%64 != 0 {
+		words++
+	}
+	if len(payload)%8 != 0 || uint64(len(payload)/8) != words {
+		return ErrFilterCorrupted
+	}
+
+	bits := make([]uint64, len(payload)/8)
+	for i := range bits {
+		bits[i] = binary.LittleEndian.Uint64(payload[8*i:])
+	}
+	bf.m, bf.k, bf.scheme, bf.bits = h.length, int(h.k), h.scheme, bits
+	return nil
+}
+
+func (bf *SizedBloomFilter) WriteTo(w io.Writer) (int64, error) {
+	return writeFilter(w, bf)
+}
+
+func (bf *SizedBloomFilter) ReadFrom(r io.Reader) (int64, error) {
+	return readFilterInto(r, bf)
+}
+
+type binaryFilter interface {
+	MarshalBinary() ([]byte, error)
+	UnmarshalBinary(data []byte) error
+}
+
+func writeFilter(w io.Writer, f binaryFilter) (int64, error) {
+	data, err := f.MarshalBinary()
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+func readFilterInto(r io.Reader, f binaryFilter) (int64, error) {
+	data, n, err := readFilter(r)
+	if err != nil {
+		return n, err
+	}
+	return n, f.UnmarshalBinary(data)
+}
@@ -0,0 +1,249 @@
+package bloomfilter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"testing"
+)
+
+func TestBloomFilter_MarshalRoundTrip(t *testing.T) {
+	bf := NewBloomFilter(32)
+	for _, s := range generateTestStrings()[:5] {
+		bf.Add(s)
+	}
+
+	data, err := bf.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var loaded BloomFilter
+	if err := loaded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary: %v", err)
+	}
+	if loaded.filter_len != 32 || loaded.bitmask != bf.bitmask {
+		t.Errorf("loaded filter_len=%d bitmask=%b, expected 32 and %b", loaded.filter_len, loaded.bitmask, bf.bitmask)
+	}
+
+	same := NewBloomFilter(32)
+	if err := same.UnmarshalBinary(data); err != nil {
+		t.Errorf("loading into filter with same parameters: %v", err)
+	}
+}
+
+func TestCountingBloomFilter_MarshalRoundTrip(t *testing.T) {
+	cbf := NewCountingBloomFilter(32)
+	for _, s := range generateTestStrings() {
+		cbf.Add(s)
+	}
+	cbf.Add("0123456789")
+
+	data, _ := cbf.MarshalBinary()
+	var loaded CountingBloomFilter
+	if err := loaded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary: %v", err)
+	}
+	if !bytes.Equal(loaded.counters, cbf.counters) {
+		t.Errorf("counters differ after round trip")
+	}
+
+	// loaded filter must own its counters
+	data[len(data)-5]++
+	if !bytes.Equal(loaded.counters, cbf.counters) {
+		t.Error("loaded counters share memory with serialized data")
+	}
+}
+
+func TestSizedBloomFilter_MarshalRoundTrip(t *testing.T) {
+	bf, _ := NewSizedBloomFilter(1000, 0.01)
+	for i := range 1000 {
+		bf.Add(fmt.Sprintf("key-%d", i))
+	}
+
+	data, _ := bf.MarshalBinary()
+	var loaded SizedBloomFilter
+	if err := loaded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary: %v", err)
+	}
+	if loaded.BitLen() != bf.BitLen() || loaded.K() != bf.K() {
+		t.Errorf("loaded m=%d k=%d, expected m=%d k=%d", loaded.BitLen(), loaded.K(), bf.BitLen(), bf.K())
+	}
+	for i := range 1000 {
+		if !loaded.IsValue(fmt.Sprintf("key-%d", i)) {
+			t.Fatalf("key-%d lost in round trip", i)
+		}
+	}
+}
+
+func TestUnmarshal_ParameterMismatch(t *testing.T) {
+	bf := NewBloomFilter(32)
+	bf.Add("x")
+	data, _ := bf.MarshalBinary()
+
+	if err := NewBloomFilter(16).UnmarshalBinary(data); !errors.Is(err, ErrFilterMismatch) {
+		t.Errorf("other length: err = %v, expected ErrFilterMismatch", err)
+	}
+	if err := NewCountingBloomFilter(32).UnmarshalBinary(data); !errors.Is(err, ErrFilterMismatch) {
+		t.Errorf("other kind: err = %v, expected ErrFilterMismatch", err)
+	}
+
+	sized, _ := NewSizedBloomFilterMK(1024, 3)
+	sizedData, _ := sized.MarshalBinary()
+	other, _ := NewSizedBloomFilterMK(1024, 4)
+	if err := other.UnmarshalBinary(sizedData); !errors.Is(err, ErrFilterMismatch) {
+		t.Errorf("other k: err = %v, expected ErrFilterMismatch", err)
+	}
+}
+
+func TestUnmarshal_Corrupted(t *testing.T) {
+	bf := NewBloomFilter(32)
+	bf.Add("x")
+	data, _ := bf.MarshalBinary()
+
+	flipped := bytes.Clone(data)
+	flipped[len(flipped)-6] ^= 0xff
+	if err := new(BloomFilter).UnmarshalBinary(flipped); !errors.Is(err, ErrFilterCorrupted) {
+		t.Errorf("flipped byte: err = %v, expected ErrFilterCorrupted", err)
+	}
+	if err := new(BloomFilter).UnmarshalBinary(data[:len(data)-1]); !errors.Is(err, ErrFilterCorrupted) {
+		t.Errorf("truncated: err = %v, expected ErrFilterCorrupted", err)
+	}
+	if err := new(BloomFilter).UnmarshalBinary([]byte("nope")); !errors.Is(err, ErrFilterCorrupted) {
+		t.Errorf("garbage: err = %v, expected ErrFilterCorrupted", err)
+	}
+}
+
+func TestCountingBloomFilter_UnmarshalCorruptedLength(t *testing.T) {
+	for _, length := range []uint64{1 << 62, 1 << 40, 33} {
+		data := encodeFilter(newHeader(kindCounting, HashLab, 2, length), []byte{0})
+		if err := new(CountingBloomFilter).UnmarshalBinary(data); !errors.Is(err, ErrFilterCorrupted) {
+			t.Errorf("length %d with a 1-byte payload: err = %v, expected ErrFilterCorrupted", length, err)
+		}
+
+		v1 := bytes.Clone(data)
+		v1[len(filterMagic)] = 1
+		v1 = encodeRaw(v1[:len(v1)-4])
+		if err := new(CountingBloomFilter).UnmarshalBinary(v1); !errors.Is(err, ErrFilterCorrupted) {
+			t.Errorf("version 1, length %d with a 1-byte payload: err = %v, expected ErrFilterCorrupted", length, err)
+		}
+	}
+}
+
+func TestSizedBloomFilter_UnmarshalCorruptedHeader(t *testing.T) {
+	cases := []struct {
+		name      string
+		k, length uint64
+		payload   []byte
+	}{
+		{"length overflows word count", 3, math.MaxUint64, nil},
+		{"length near 2^64", 3, math.MaxUint64 - 62, make([]byte, 8)},
+		{"k above MaxInt", math.MaxUint64, 64, make([]byte, 8)},
+		{"k above MAX_HASHES", MAX_HASHES + 1, 64, make([]byte, 8)},
+		{"payload not whole words", 3, 64, make([]byte, 12)},
+	}
+	for _, tc := range cases {
+		data := encodeFilter(newHeader(kindSized, HashDouble, tc.k, tc.length), tc.payload)
+		if err := new(SizedBloomFilter).UnmarshalBinary(data); !errors.Is(err, ErrFilterCorrupted) {
+			t.Errorf("%s: err = %v, expected ErrFilterCorrupted", tc.name, err)
+		}
+	}
+}
+
+func TestUnmarshal_UnknownVersion(t *testing.T) {
+	h := newHeader(kindBloom, HashLab, 2, 32)
+	data := encodeFilter(h, make([]byte, 4))
+	data[len(filterMagic)] = filterVersion + 1
+	// re-sign so only the version is wrong
+	data = encodeRaw(data[:len(data)-4])
+
+	if err := new(BloomFilter).UnmarshalBinary(data); !errors.Is(err, ErrFilterVersion) {
+		t.Errorf("err = %v, expected ErrFilterVersion", err)
+	}
+}
+
+func TestCountingBloomFilter_UnmarshalVersion1(t *testing.T) {
+	// version 1 stored one byte per counter
+	counters := make([]byte, 32)
+	counters[3], counters[7] = 2, 200
+	data := encodeFilter(newHeader(kindCounting, HashLab, 2, 32), counters)
+	data[len(filterMagic)] = 1
+	data = encodeRaw(data[:len(data)-4])
+
+	var cbf CountingBloomFilter
+	if err := cbf.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary: %v", err)
+	}
+	if cbf.counter(3) != 2 || cbf.counter(7) != COUNTER_MAX || !cbf.Overflowed() {
+		t.Errorf("counters %d, %d, overflowed %v; expected 2, %d, true", cbf.counter(3), cbf.counter(7), cbf.Overflowed(), COUNTER_MAX)
+	}
+}
+
+func TestSizedBloomFilter_LabSchemeRoundTrip(t *testing.T) {
+	cbf := NewCountingBloomFilter(500)
+	cbf.Add("cat")
+	data, _ := cbf.ToBitFilter().MarshalBinary()
+
+	var loaded SizedBloomFilter
+	if err := loaded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary: %v", err)
+	}
+	if loaded.Scheme() != HashLab || !loaded.IsValue("cat") {
+		t.Errorf("scheme %v, IsValue(cat) %v; expected lab, true", loaded.Scheme(), loaded.IsValue("cat"))
+	}
+
+	double, _ := NewSizedBloomFilterMK(500, 2)
+	if err := double.UnmarshalBinary(data); !errors.Is(err, ErrFilterMismatch) {
+		t.Errorf("lab data into double hashing filter: err = %v, expected ErrFilterMismatch", err)
+	}
+}
+
+func TestWriteToReadFrom_Stream(t *testing.T) {
+	bf := NewBloomFilter(32)
+	bf.Add("a")
+	cbf := NewCountingBloomFilter(64)
+	cbf.Add("b")
+
+	var buf bytes.Buffer
+	n1, err := bf.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n2, err := cbf.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var loadedBF BloomFilter
+	var loadedCBF CountingBloomFilter
+	r1, err := loadedBF.ReadFrom(&buf)
+	if err != nil || r1 != n1 {
+		t.Fatalf("BloomFilter ReadFrom = %d, %v, expected %d bytes", r1, err, n1)
+	}
+	r2, err := loadedCBF.ReadFrom(&buf)
+	if err != nil || r2 != n2 {
+		t.Fatalf("CountingBloomFilter ReadFrom = %d, %v, expected %d bytes", r2, err, n2)
+	}
+	if !loadedBF.IsValue("a") || !loadedCBF.IsValue("b") {
+		t.Error("filters lost their content")
+	}
+
+	if _, err := new(BloomFilter).ReadFrom(&buf); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("empty stream: err = %v, expected io.ErrUnexpectedEOF", err)
+	}
+}
+
+func TestHashScheme_String(t *testing.T) {
+	if HashLab.String() != "lab" || HashDouble.String() != "double" || HashScheme(9).String() != "HashScheme(9)" {
+		t.Error("unexpected HashScheme names")
+	}
+}
+
+// encodeRaw appends a valid checksum to an already encoded body.
+func encodeRaw(body []byte) []byte {
+	return binary.BigEndian.AppendUint32(bytes.Clone(body), crc32.ChecksumIEEE(body))
+}
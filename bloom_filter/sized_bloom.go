@@ -0,0 +1,159 @@
+package bloomfilter
+
+import (
+	"errors"
+	"math"
+)
+
+// MAX_HASHES bounds k: 64 hashes already mean p ≈ 2^-64, anything above is a bug or corrupted data.
+const MAX_HASHES = 64
+
+var (
+	ErrInvalidCapacity = errors.New("expected number of items must be positive")
+	ErrInvalidFPRate   = errors.New("false positive rate must be in (0, 1)")
+	ErrInvalidParams   = errors.New("bit length must be positive and number of hashes in 1..MAX_HASHES")
+)
+
+// SizedBloomFilter is the non-lab Bloom filter: bits live in a []uint64 bitset of any length m,
+// and k positions per key come from double hashing, so nothing is tied to 32 bits and two magic numbers.
+//
+// Sizing for n expected items and target false positive rate p (standard results):
+//
+//	m = -n*ln(p) / (ln 2)^2    bits
+//	k = (m/n) * ln 2           hashes
+//
+// E.g. n = 1M, p = 1% gives m ≈ 9.59M bits (1.2 MB) and k = 7.
+//
+// A filter made by CountingBloomFilter.ToBitFilter keeps the lab positions (HashLab, k = 2),
+// so it answers exactly like the counting filter it came from.
+type SizedBloomFilter struct {
+	m      uint64
+	k      int
+	scheme HashScheme
+	bits   []uint64
+}
+
+// OptimalParams returns the bit length m and number of hashes k for n items at false positive rate p.
+// k is capped at MAX_HASHES, which only matters for p below ~1e-19.
+// Time: O(1)
+// Space: O(1)
+func OptimalParams(n int, p float64) (m uint64, k int, err error) {
+	if n <= 0 {
+		return 0, 0, ErrInvalidCapacity
+	}
+	if p <= 0 || p >= 1 {
+		return 0, 0, ErrInvalidFPRate
+	}
+
+	m = uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	k = int(math.Round(float64(m) / float64(n) * math.Ln2))
+	return m, min(max(k, 1), MAX_HASHES), nil
+}
+
+// NewSizedBloomFilter builds a filter for n expected items and target false positive rate p.
+// Time: O(m/64) for bitset allocation
+// Space: O(m/64)
+func NewSizedBloomFilter(n int, p float64) (*SizedBloomFilter, error) {
+	m, k, err := OptimalParams(n, p)
+	if err != nil {
+		return nil, err
+	}
+	return NewSizedBloomFilterMK(m, k)
+}
+
+// NewSizedBloomFilterMK builds a filter with explicit bit length m and k hashes.
+// Time: O(m/64) for bitset allocation
+// Space: O(m/64)
+func NewSizedBloomFilterMK(m uint64, k int) (*SizedBloomFilter, error) {
+	if m == 0 || k <= 0 || k > MAX_HASHES {
+		return nil, ErrInvalidParams
+	}
+	return &SizedBloomFilter{
+		m:      m,
+		k:      k,
+		scheme: HashDouble,
+		bits:   make([]uint64, (m+63)/64),
+	}, nil
+}
+
+// newLabSizedBloomFilter builds an m-bit filter with the lab hashes, the same positions as BloomFilter has.
+// Time: O(m/64)
+// Space: O(m/64)
+func newLabSizedBloomFilter(m uint64) *SizedBloomFilter {
+	return &SizedBloomFilter{
+		m:      m,
+		k:      2,
+		scheme: HashLab,
+		bits:   make([]uint64, (m+63)/64),
+	}
+}
+
+// labHasher is BloomFilter.hasher for any filter length.
+// Time: O(len(s))
+// Space: O(1)
+func labHasher(s string, salt int, n uint64) uint64 {
+	hashSum := uint64(0)
+	for _, char := range s {
+		hashSum = (hashSum*uint64(salt) + uint64(char)) % n
+	}
+	return hashSum
+}
+
+// All operations are O(len(s) + k).
+
+func (bf *SizedBloomFilter) Add(s string) {
+	if bf.scheme == HashLab {
+		bf.set(labHasher(s, MAGIC_1, bf.m))
+		bf.set(labHasher(s, MAGIC_2, bf.m))
+		return
+	}
+
+	h1, h2 := baseHashes(s)
+	for i := range bf.k {
+		bf.set((h1 + uint64(i)*h2) % bf.m)
+	}
+}
+
+func (bf *SizedBloomFilter) IsValue(s string) bool {
+	if bf.scheme == HashLab {
+		return bf.test(labHasher(s, MAGIC_1, bf.m)) && bf.test(labHasher(s, MAGIC_2, bf.m))
+	}
+
+	h1, h2 := baseHashes(s)
+	for i := range bf.k {
+		if !bf.test((h1 + uint64(i)*h2) % bf.m) {
+			return false
+		}
+	}
+	return true
+}
+
+func (bf *SizedBloomFilter) set(pos uint64) {
+	bf.bits[pos/64] |= 1 << (pos % 64)
+}
+
+func (bf *SizedBloomFilter) test(pos uint64) bool {
+	return bf.bits[pos/64]&(1<<(pos%64)) != 0
+}
+
+// BitLen returns m, the number of bits in the filter.
+func (bf *SizedBloomFilter) BitLen() uint64 {
+	return bf.m
+}
+
+// K returns the number of hash positions per key.
+func (bf *SizedBloomFilter) K() int {
+	return bf.k
+}
+
+// Scheme returns how bit positions are computed.
+func (bf *SizedBloomFilter) Scheme() HashScheme {
+	return bf.scheme
+}
+
+// FPRate returns the expected false positive rate after n distinct insertions: (1 - e^(-kn/m))^k.
+// Time: O(1)
+// Space: O(1)
+func (bf *SizedBloomFilter) FPRate(n int) float64 {
+	return math.Pow(1-math.Exp(-float64(bf.k)*float64(n)/float64(bf.m)), float64(bf.k))
+}
@@ -0,0 +1,151 @@
+package bloomfilter
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestOptimalParams(t *testing.T) {
+	tests := []struct {
+		n     int
+		p     float64
+		wantM uint64
+		wantK int
+	}{
+		{1_000_000, 0.01, 9_585_059, 7},
+		{1000, 0.001, 14_378, 10},
+		{1, 0.5, 2, 1},
+	}
+
+	for _, tt := range tests {
+		m, k, err := OptimalParams(tt.n, tt.p)
+		if err != nil {
+			t.Fatalf("OptimalParams(%d, %v): %v", tt.n, tt.p, err)
+		}
+		if m != tt.wantM || k != tt.wantK {
+			t.Errorf("OptimalParams(%d, %v) = %d, %d, expected %d, %d", tt.n, tt.p, m, k, tt.wantM, tt.wantK)
+		}
+	}
+}
+
+func TestOptimalParams_Invalid(t *testing.T) {
+	if _, _, err := OptimalParams(0, 0.01); !errors.Is(err, ErrInvalidCapacity) {
+		t.Errorf("n=0: err = %v, expected ErrInvalidCapacity", err)
+	}
+	for _, p := range []float64{0, 1, -0.1, 1.5} {
+		if _, _, err := OptimalParams(100, p); !errors.Is(err, ErrInvalidFPRate) {
+			t.Errorf("p=%v: err = %v, expected ErrInvalidFPRate", p, err)
+		}
+	}
+	if _, err := NewSizedBloomFilterMK(0, 3); !errors.Is(err, ErrInvalidParams) {
+		t.Errorf("m=0: err = %v, expected ErrInvalidParams", err)
+	}
+	if _, err := NewSizedBloomFilterMK(1024, MAX_HASHES+1); !errors.Is(err, ErrInvalidParams) {
+		t.Errorf("k=%d: err = %v, expected ErrInvalidParams", MAX_HASHES+1, err)
+	}
+	if _, k, _ := OptimalParams(100, 1e-30); k != MAX_HASHES {
+		t.Errorf("p=1e-30: k = %d, expected the MAX_HASHES cap", k)
+	}
+}
+
+func TestSizedBloomFilter_NoFalseNegatives(t *testing.T) {
+	bf, err := NewSizedBloomFilter(10_000, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range 10_000 {
+		bf.Add(fmt.Sprintf("key-%d", i))
+	}
+	for i := range 10_000 {
+		if !bf.IsValue(fmt.Sprintf("key-%d", i)) {
+			t.Fatalf("key-%d not found after Add", i)
+		}
+	}
+}
+
+func TestSizedBloomFilter_LongerThan32Bits(t *testing.T) {
+	bf, err := NewSizedBloomFilterMK(1000, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bf.Add("0123456789")
+	high := false
+	for w := 1; w < len(bf.bits); w++ {
+		if bf.bits[w] != 0 {
+			high = true
+		}
+	}
+	if !high && bf.bits[0]>>32 == 0 {
+		t.Error("all bits landed in the low 32, the bitset is not used")
+	}
+}
+
+func TestSizedBloomFilter_FalsePositiveRate(t *testing.T) {
+	const n, p = 20_000, 0.01
+	bf, err := NewSizedBloomFilter(n, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range n {
+		bf.Add(fmt.Sprintf("member-%d", i))
+	}
+
+	const probes = 100_000
+	fp := 0
+	for i := range probes {
+		if bf.IsValue(fmt.Sprintf("outsider-%d", i)) {
+			fp++
+		}
+	}
+
+	rate := float64(fp) / probes
+	t.Logf("m=%d k=%d measured FP rate %.4f, target %.4f, predicted %.4f", bf.BitLen(), bf.K(), rate, p, bf.FPRate(n))
+	if rate > 1.5*p {
+		t.Errorf("FP rate %.4f is way above target %.4f", rate, p)
+	}
+	if math.Abs(bf.FPRate(n)-p) > p/5 {
+		t.Errorf("predicted FP rate %.4f, expected about %.4f", bf.FPRate(n), p)
+	}
+}
+
+func TestSizedBloomFilter_EmptyString(t *testing.T) {
+	bf, _ := NewSizedBloomFilter(100, 0.01)
+	if bf.IsValue("") {
+		t.Error("empty filter reports empty string")
+	}
+	bf.Add("")
+	if !bf.IsValue("") {
+		t.Error("empty string not found after Add")
+	}
+}
+
+func BenchmarkSizedBloomFilter_Add(b *testing.B) {
+	bf, _ := NewSizedBloomFilter(1_000_000, 0.01)
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bf.Add(keys[i%len(keys)])
+	}
+}
+
+func BenchmarkSizedBloomFilter_IsValue(b *testing.B) {
+	bf, _ := NewSizedBloomFilter(1_000_000, 0.01)
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+		bf.Add(keys[i])
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bf.IsValue(keys[i%len(keys)])
+	}
+}
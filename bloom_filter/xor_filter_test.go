@@ -0,0 +1,206 @@
+package bloomfilter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func keySet(prefix string, n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("%s_%d", prefix, i)
+	}
+	return keys
+}
+
+// staticFilter is what both static filters offer, tests run for each of them.
+type staticFilter interface {
+	Contains(s string) bool
+	SizeInBytes() int
+	MarshalBinary() ([]byte, error)
+}
+
+var staticBuilders = map[string]func(keys []string) (staticFilter, error){
+	"xor": func(keys []string) (staticFilter, error) {
+		return BuildXorFilter(keys)
+	},
+	"fuse": func(keys []string) (staticFilter, error) {
+		return BuildBinaryFuse8(keys)
+	},
+}
+
+func TestStaticFilters_NoFalseNegatives(t *testing.T) {
+	for name, build := range staticBuilders {
+		for _, n := range []int{0, 1, 2, 10, 1000, 100_000} {
+			t.Run(fmt.Sprintf("%s/%d", name, n), func(t *testing.T) {
+				keys := keySet("deny", n)
+				f, err := build(keys)
+				if err != nil {
+					t.Fatalf("build: %v", err)
+				}
+				for _, k := range keys {
+					if !f.Contains(k) {
+						t.Fatalf("%s not found", k)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestStaticFilters_Duplicates(t *testing.T) {
+	keys := append(keySet("deny", 1000), keySet("deny", 1000)...)
+	for name, build := range staticBuilders {
+		f, err := build(keys)
+		if err != nil {
+			t.Fatalf("%s: build with duplicates: %v", name, err)
+		}
+		if !f.Contains("deny_999") {
+			t.Errorf("%s: deny_999 not found", name)
+		}
+	}
+}
+
+func TestStaticFilters_FalsePositiveRateAndSize(t *testing.T) {
+	const n = 200_000
+	keys := keySet("member", n)
+
+	for name, build := range staticBuilders {
+		f, err := build(keys)
+		if err != nil {
+			t.Fatalf("%s: build: %v", name, err)
+		}
+
+		falsePositives := 0
+		for _, s := range keySet("candidate", n) {
+			if f.Contains(s) {
+				falsePositives++
+			}
+		}
+
+		rate := float64(falsePositives) / n
+		bitsPerKey := float64(8*f.SizeInBytes()) / n
+		t.Logf("%s: %d false positives (%.4f, expected %.4f), %.2f bits per key", name, falsePositives, rate, 1.0/256, bitsPerKey)
+		if rate > 1.5/256 {
+			t.Errorf("%s: FP rate %.4f, expected about %.4f", name, rate, 1.0/256)
+		}
+		if bitsPerKey > 10 {
+			t.Errorf("%s: %.2f bits per key, expected below 10", name, bitsPerKey)
+		}
+	}
+}
+
+func TestBinaryFuse8_SmallerThanXor(t *testing.T) {
+	keys := keySet("member", 100_000)
+	xf, _ := BuildXorFilter(keys)
+	bf, _ := BuildBinaryFuse8(keys)
+	if bf.SizeInBytes() >= xf.SizeInBytes() {
+		t.Errorf("binary fuse %d bytes, xor %d bytes", bf.SizeInBytes(), xf.SizeInBytes())
+	}
+}
+
+func TestPeel_RetriesWithNewSeed(t *testing.T) {
+	// 10 keys in 12 slots: peeling often gets stuck, but some seed works
+	base := xorHashes(keySet("k", 10))
+	f := &XorFilter{blockLength: 4, fingerprints: make([]uint8, 12)}
+
+	failed, succeeded := 0, false
+	for attempt := range XOR_MAX_ATTEMPTS {
+		f.seed = seedFor(attempt)
+		if peel(base, f.seed, f.fingerprints, f.slots) {
+			succeeded = true
+			break
+		}
+		failed++
+	}
+	if failed == 0 || !succeeded {
+		t.Fatalf("%d failed attempts, succeeded: %v; expected failures and then success", failed, succeeded)
+	}
+	for i := range 10 {
+		if !f.Contains(fmt.Sprintf("k_%d", i)) {
+			t.Fatalf("k_%d not found after retried build", i)
+		}
+	}
+}
+
+func TestXorFilter_MarshalRoundTrip(t *testing.T) {
+	keys := keySet("deny", 5000)
+	f, _ := BuildXorFilter(keys)
+
+	var buf bytes.Buffer
+	if _, err := f.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var loaded XorFilter
+	if _, err := loaded.ReadFrom(&buf); err != nil {
+		t.Fatalf("ReadFrom: %v", err)
+	}
+	for _, k := range keys {
+		if !loaded.Contains(k) {
+			t.Fatalf("%s lost in round trip", k)
+		}
+	}
+}
+
+func TestBinaryFuse8_MarshalRoundTrip(t *testing.T) {
+	keys := keySet("deny", 5000)
+	f, _ := BuildBinaryFuse8(keys)
+
+	data, _ := f.MarshalBinary()
+	var loaded BinaryFuse8
+	if err := loaded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary: %v", err)
+	}
+	for _, k := range keys {
+		if !loaded.Contains(k) {
+			t.Fatalf("%s lost in round trip", k)
+		}
+	}
+	for _, k := range keySet("other", 5000) {
+		if loaded.Contains(k) != f.Contains(k) {
+			t.Fatalf("loaded filter answers differently for %s", k)
+		}
+	}
+}
+
+func TestStaticFilters_UnmarshalMismatch(t *testing.T) {
+	xf, _ := BuildXorFilter(keySet("a", 100))
+	data, _ := xf.MarshalBinary()
+
+	if err := new(BinaryFuse8).UnmarshalBinary(data); !errors.Is(err, ErrFilterMismatch) {
+		t.Errorf("xor into fuse: err = %v, expected ErrFilterMismatch", err)
+	}
+	other, _ := BuildXorFilter(keySet("b", 1000))
+	if err := other.UnmarshalBinary(data); !errors.Is(err, ErrFilterMismatch) {
+		t.Errorf("other size: err = %v, expected ErrFilterMismatch", err)
+	}
+}
+
+func BenchmarkXorFilter_Contains(b *testing.B) {
+	keys := keySet("key", benchKeys)
+	f, _ := BuildXorFilter(keys)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		f.Contains(keys[i%benchKeys])
+	}
+}
+
+func BenchmarkBinaryFuse8_Contains(b *testing.B) {
+	keys := keySet("key", benchKeys)
+	f, _ := BuildBinaryFuse8(keys)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		f.Contains(keys[i%benchKeys])
+	}
+}
+
+func BenchmarkBuildBinaryFuse8_100K(b *testing.B) {
+	keys := keySet("key", 100_000)
+	for i := 0; i < b.N; i++ {
+		BuildBinaryFuse8(keys)
+	}
+}
@@ -0,0 +1,90 @@
+package cache
+
+import (
+	"container/list"
+	"slices"
+)
+
+// Plain LFU never forgets: a key that was hot yesterday keeps its huge freq and can't be evicted
+// even after nobody needs it anymore (so called cache pollution). Two classic remedies are supported:
+//
+//   - AgingHalving: every N operations all frequencies are halved. Old popularity fades exponentially,
+//     so new hot keys catch up with old ones in a few periods.
+//
+//   - AgingDynamic, LFU-DA (Arlitt et al.): the cache keeps an "age" L, equal to the priority of the last
+//     evicted entry. New entries start at L + 1 instead of 1, and each hit adds 1 as usual.
+//     Old entries do not lose anything, but newcomers start closer and closer to them,
+//     so in time formerly hot keys become the least valuable ones and get evicted.
+//
+// In LFU-DA mode cacheEntry.freq holds the priority (L at insertion + hits). The original paper recomputes
+// priority as hits + current L on every hit, which may jump over many buckets and breaks minFreq++ trick;
+// the +1 variant used here keeps Get O(1) and has the same "newcomers inherit the age" effect.
+
+// AgingMode selects how LFUCache fights frequency pollution.
+type AgingMode int
+
+const (
+	AgingNone AgingMode = iota
+	AgingHalving
+	AgingDynamic
+)
+
+// WithHalvingAging halves every frequency after each `every` operations (Get hits and Puts).
+// Halving is O(n), so `every` should be at least capacity to keep operations O(1) amortized.
+func WithHalvingAging(every int) Option {
+	return func(o *options) {
+		o.aging = AgingHalving
+		o.halvingPeriod = every
+	}
+}
+
+// WithDynamicAging turns on LFU-DA.
+func WithDynamicAging() Option {
+	return func(o *options) {
+		o.aging = AgingDynamic
+	}
+}
+
+// Time: O(1), O(n + F log F) once per halvingPeriod operations
+// Space: O(1)
+func (c *LFUCache[K, V]) tickAging() {
+	if c.aging != AgingHalving || c.halvingPeriod <= 0 {
+		return
+	}
+
+	c.ops++
+	if c.ops >= c.halvingPeriod {
+		c.ops = 0
+		c.halveFrequencies()
+	}
+}
+
+// halveFrequencies rebuilds freqMap with every freq divided by two (but not below 1).
+// Buckets 2f and 2f+1 merge into f; they are moved in ascending order and each bucket from the oldest
+// entry to the newest, so LRU order inside the old buckets is kept and entries of 2f+1 count as more recent.
+// Time: O(n + F log F) where F = number of distinct frequencies
+// Space: O(F)
+func (c *LFUCache[K, V]) halveFrequencies() {
+	freqs := make([]int, 0, len(c.freqMap))
+	for freq := range c.freqMap {
+		freqs = append(freqs, freq)
+	}
+	slices.Sort(freqs)
+
+	halved := make(map[int]*list.List, len(freqs))
+	for _, freq := range freqs {
+		bucket := c.freqMap[freq]
+		newFreq := max(1, freq/2)
+		for elem := bucket.Back(); elem != nil; elem = elem.Prev() {
+			e := elem.Value.(*cacheEntry[K, V])
+			e.freq = newFreq
+			if halved[newFreq] == nil {
+				halved[newFreq] = list.New()
+			}
+			c.cache[e.key] = halved[newFreq].PushFront(e)
+		}
+	}
+
+	c.freqMap = halved
+	c.recomputeMinFreq()
+}
@@ -0,0 +1,192 @@
+package cache
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"testing"
+)
+
+// shiftWorkload makes old keys very hot, then switches to a different set of keys
+// which are accessed in bursts. Returns how many of the old keys are still stored.
+func shiftWorkload(c *LFUCache[string, int]) int {
+	old := []string{"old_a", "old_b", "old_c", "old_d"}
+	for _, key := range old {
+		c.Put(key, 0)
+		for range 50 {
+			c.Get(key)
+		}
+	}
+
+	for round := range 30 {
+		for i := range 4 {
+			key := fmt.Sprint("new_", i)
+			if _, ok := c.Get(key); !ok {
+				c.Put(key, round)
+			}
+			for range 5 {
+				c.Get(key)
+			}
+		}
+	}
+
+	survived := 0
+	for _, key := range old {
+		if _, ok := c.cache[key]; ok {
+			survived++
+		}
+	}
+	return survived
+}
+
+func TestAging_PlainLFUKeepsPollution(t *testing.T) {
+	c := NewLFU[string, int](4)
+	if survived := shiftWorkload(c); survived < 3 {
+		t.Errorf("%d old keys survived, plain LFU is expected to keep them (this test documents the problem)", survived)
+	}
+}
+
+func TestAging_HalvingEvictsFormerlyHotKeys(t *testing.T) {
+	c := NewLFU[string, int](4, WithHalvingAging(20))
+	if survived := shiftWorkload(c); survived != 0 {
+		t.Errorf("%d old keys survived the workload shift, expected 0", survived)
+	}
+}
+
+func TestAging_DynamicEvictsFormerlyHotKeys(t *testing.T) {
+	c := NewLFU[string, int](4, WithDynamicAging())
+	if survived := shiftWorkload(c); survived != 0 {
+		t.Errorf("%d old keys survived the workload shift, expected 0", survived)
+	}
+}
+
+func TestHalveFrequencies(t *testing.T) {
+	c := NewLFU[string, int](10)
+	c.Put("a", 1)
+	c.Put("b", 2)
+	c.Put("c", 3)
+	for range 9 {
+		c.Get("a")
+	}
+	for range 4 {
+		c.Get("b")
+	}
+
+	c.halveFrequencies()
+
+	expected := map[string]int{"a": 5, "b": 2, "c": 1}
+	for key, freq := range expected {
+		if got := c.cache[key].Value.(*cacheEntry[string, int]).freq; got != freq {
+			t.Errorf("%s.freq is %d, expected %d", key, got, freq)
+		}
+	}
+	if c.minFreq != 1 {
+		t.Errorf("minFreq is %d, expected 1", c.minFreq)
+	}
+	for freq, bucket := range c.freqMap {
+		for elem := bucket.Front(); elem != nil; elem = elem.Next() {
+			if elem.Value.(*cacheEntry[string, int]).freq != freq {
+				t.Errorf("entry in bucket %d has freq %d", freq, elem.Value.(*cacheEntry[string, int]).freq)
+			}
+		}
+	}
+}
+
+func TestHalveFrequencies_KeepsLRUOrderInBucket(t *testing.T) {
+	c := NewLFU[string, int](10)
+	c.Put("older", 1)
+	c.Put("newer", 2)
+	c.Get("older")
+	c.Get("newer")
+
+	c.halveFrequencies()
+	c.capacity = 2
+	c.Put("x", 3)
+
+	if _, ok := c.cache["older"]; ok {
+		t.Error("older should be evicted first, it is the LRU entry of the merged bucket")
+	}
+}
+
+func TestHalvingAging_TriggersEveryPeriod(t *testing.T) {
+	c := NewLFU[string, int](10, WithHalvingAging(10))
+	c.Put("a", 1)
+	for range 8 {
+		c.Get("a")
+	}
+	if freq := c.cache["a"].Value.(*cacheEntry[string, int]).freq; freq != 9 {
+		t.Fatalf("freq is %d, expected 9 before halving", freq)
+	}
+
+	c.Get("a") // 10th operation
+	if freq := c.cache["a"].Value.(*cacheEntry[string, int]).freq; freq != 5 {
+		t.Errorf("freq is %d, expected 5 after halving", freq)
+	}
+}
+
+func TestDynamicAging_NewEntriesInheritAge(t *testing.T) {
+	c := NewLFU[string, int](2, WithDynamicAging())
+	c.Put("a", 1)
+	c.Put("b", 2)
+	for range 4 {
+		c.Get("a")
+	}
+	c.Get("b")
+	c.Get("b")
+
+	c.Put("c", 3) // evicts b with priority 3
+
+	if c.age != 3 {
+		t.Errorf("age is %d, expected 3 (priority of evicted b)", c.age)
+	}
+	if freq := c.cache["c"].Value.(*cacheEntry[string, int]).freq; freq != 4 {
+		t.Errorf("c.freq is %d, expected age+1 = 4", freq)
+	}
+	if c.minFreq != 4 {
+		t.Errorf("minFreq is %d, expected 4", c.minFreq)
+	}
+
+	c.Put("d", 4)
+	if _, ok := c.cache["c"]; ok {
+		t.Error("c should be evicted, it has the lowest priority")
+	}
+	if _, ok := c.cache["a"]; !ok {
+		t.Error("a (priority 5) should still be stored")
+	}
+}
+
+func TestDynamicAging_MinFreqStaysInBucketAfterEviction(t *testing.T) {
+	c := NewLFU[string, int](3, WithDynamicAging())
+	c.Put("a", 1)
+	c.Put("b", 2)
+	c.Put("c", 3)
+
+	c.Put("d", 4) // evicts a at priority 1, b and c stay at priority 1
+
+	if c.minFreq != 1 {
+		t.Errorf("minFreq is %d, expected 1 (b and c are still in bucket 1)", c.minFreq)
+	}
+	if victim, _ := c.victim(); victim.key != "b" {
+		t.Errorf("next victim is %q, expected b", victim.key)
+	}
+}
+
+func TestDynamicAging_EmptyBucketsAreDropped(t *testing.T) {
+	c := NewLFU[int, int](100, WithDynamicAging())
+	rng := rand.New(rand.NewPCG(1, 2))
+
+	for range 200_000 {
+		key := int(rng.Uint32N(1000))
+		if _, ok := c.Get(key); !ok {
+			c.Put(key, key)
+		}
+	}
+
+	if len(c.freqMap) > c.Len() {
+		t.Errorf("%d frequency buckets for %d entries", len(c.freqMap), c.Len())
+	}
+	for freq, bucket := range c.freqMap {
+		if bucket.Len() == 0 {
+			t.Errorf("bucket %d is empty but still in freqMap", freq)
+		}
+	}
+}
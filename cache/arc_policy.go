@@ -0,0 +1,110 @@
+package cache
+
+// ARCPolicy is Adaptive Replacement Cache (Megiddo & Modha, FAST'03).
+//
+//   - T1: keys seen once recently (recency), T2: keys seen at least twice (frequency).
+//   - B1, B2: ghost lists of keys recently evicted from T1 and T2 (keys only, no values).
+//   - p: target size of T1. A miss that hits B1 means "T1 was too small" and grows p,
+//     a miss that hits B2 shrinks it, so the cache keeps tuning itself between LRU and LFU behaviour.
+//
+// Resident keys (T1 + T2) never exceed capacity, ghosts add up to another capacity of keys.
+type ARCPolicy[K comparable] struct {
+	capacity int
+	p        int
+	t1, t2   *keyList[K]
+	b1, b2   *keyList[K]
+}
+
+// Time: O(1)
+// Space: O(capacity) when full, plus up to capacity ghost keys
+func NewARCPolicy[K comparable](capacity int) *ARCPolicy[K] {
+	return &ARCPolicy[K]{
+		capacity: capacity,
+		t1:       newKeyList[K](),
+		t2:       newKeyList[K](),
+		b1:       newKeyList[K](),
+		b2:       newKeyList[K](),
+	}
+}
+
+// Time: O(1)
+// Space: O(1)
+func (a *ARCPolicy[K]) Hit(key K) {
+	if a.t1.Remove(key) {
+		a.t2.PushFront(key)
+		return
+	}
+	a.t2.MoveToFront(key)
+}
+
+// Time: O(1)
+// Space: O(1)
+func (a *ARCPolicy[K]) Insert(key K) (K, bool) {
+	if a.capacity <= 0 {
+		return key, true
+	}
+
+	switch {
+	case a.b1.Contains(key):
+		a.p = min(a.capacity, a.p+max(a.b2.Len()/a.b1.Len(), 1))
+		victim, evicted := a.replace(false)
+		a.b1.Remove(key)
+		a.t2.PushFront(key)
+		return victim, evicted
+
+	case a.b2.Contains(key):
+		a.p = max(0, a.p-max(a.b1.Len()/a.b2.Len(), 1))
+		victim, evicted := a.replace(true)
+		a.b2.Remove(key)
+		a.t2.PushFront(key)
+		return victim, evicted
+	}
+
+	var victim K
+	evicted := false
+	if a.t1.Len()+a.b1.Len() >= a.capacity {
+		if a.t1.Len() < a.capacity {
+			a.b1.PopBack()
+			victim, evicted = a.replace(false)
+		} else {
+			victim, evicted = a.t1.PopBack()
+		}
+	} else if total := a.t1.Len() + a.t2.Len() + a.b1.Len() + a.b2.Len(); total >= a.capacity {
+		if total >= 2*a.capacity {
+			a.b2.PopBack()
+		}
+		victim, evicted = a.replace(false)
+	}
+
+	a.t1.PushFront(key)
+	return victim, evicted
+}
+
+// replace is REPLACE(x) from the paper: moves LRU of T1 or T2 into its ghost list.
+// Only does something when the cache is actually full: after Remove there may be free room.
+// Time: O(1)
+// Space: O(1)
+func (a *ARCPolicy[K]) replace(inB2 bool) (K, bool) {
+	if a.t1.Len()+a.t2.Len() < a.capacity {
+		var zero K
+		return zero, false
+	}
+
+	if a.t1.Len() > 0 && (a.t2.Len() == 0 || a.t1.Len() > a.p || (inB2 && a.t1.Len() == a.p)) {
+		victim, _ := a.t1.PopBack()
+		a.b1.PushFront(victim)
+		return victim, true
+	}
+
+	victim, _ := a.t2.PopBack()
+	a.b2.PushFront(victim)
+	return victim, true
+}
+
+// Time: O(1)
+// Space: O(1)
+func (a *ARCPolicy[K]) Remove(key K) {
+	if !a.t1.Remove(key) {
+		a.t2.Remove(key)
+	}
+}
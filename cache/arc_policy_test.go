@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestARCPolicy_SecondHitMovesToT2(t *testing.T) {
+	p := NewARCPolicy[string](4)
+	p.Insert("a")
+	if !p.t1.Contains("a") {
+		t.Fatal("new key should land in T1")
+	}
+
+	p.Hit("a")
+	if !p.t2.Contains("a") || p.t1.Contains("a") {
+		t.Error("hit key should move from T1 to T2")
+	}
+}
+
+func TestARCPolicy_GhostHitAdaptsP(t *testing.T) {
+	p := NewARCPolicy[string](2)
+	p.Insert("a")
+	p.Insert("b")
+	p.Hit("b")
+	p.Insert("c") // evicts a from T1 into B1
+
+	if !p.b1.Contains("a") {
+		t.Fatal("a should be remembered in B1")
+	}
+
+	before := p.p
+	p.Insert("a")
+	if p.p <= before {
+		t.Errorf("p is %d, expected to grow after B1 ghost hit (was %d)", p.p, before)
+	}
+	if !p.t2.Contains("a") {
+		t.Error("key coming back from B1 should go to T2")
+	}
+}
+
+func TestARCPolicy_ScanResistance(t *testing.T) {
+	c := NewPolicyCache[string, int](NewARCPolicy[string](10))
+
+	for i := range 5 {
+		key := fmt.Sprint("hot_", i)
+		c.Put(key, i)
+		c.Get(key)
+	}
+	for i := range 100 {
+		c.Put(fmt.Sprint("scan_", i), i)
+	}
+
+	for i := range 5 {
+		if _, ok := c.Get(fmt.Sprint("hot_", i)); !ok {
+			t.Errorf("hot_%d should survive the scan in T2", i)
+		}
+	}
+}
+
+func TestARCPolicy_GhostsBounded(t *testing.T) {
+	p := NewARCPolicy[int](8)
+	c := NewPolicyCache[int, int](p)
+	for i := range 1000 {
+		c.Put(i%50, i)
+		if i%3 == 0 {
+			c.Get(i % 50)
+		}
+		resident := p.t1.Len() + p.t2.Len()
+		total := resident + p.b1.Len() + p.b2.Len()
+		if resident > 8 || total > 16 {
+			t.Fatalf("resident %d, total %d after %d inserts, expected at most 8 and 16", resident, total, i+1)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package cache
+
+// Cache is what all caches in this package have in common, so the caller can depend on it
+// and switch implementations (or eviction policies, see policy.go) without touching the code around.
+//
+// NativeCache is the exception: it is the lab-task cache with string keys and (T, error) Get,
+// and its signature stays as the task requires.
+type Cache[K comparable, V any] interface {
+	Get(key K) (V, bool)
+	Put(key K, value V)
+	Delete(key K) bool
+	Len() int
+}
+
+var (
+	_ Cache[string, any] = (*LFUCache[string, any])(nil)
+	_ Cache[string, any] = (*SyncLFUCache[string, any])(nil)
+	_ Cache[string, any] = (*ShardedLFUCache[string, any])(nil)
+	_ Cache[string, any] = (*TinyLFUCache[string, any])(nil)
+	_ Cache[string, any] = (*PolicyCache[string, any])(nil)
+	_ Cache[string, any] = (*LoadingCache[string, any])(nil)
+)
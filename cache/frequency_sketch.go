@@ -0,0 +1,105 @@
+package cache
+
+import "hash/maphash"
+
+const (
+	sketchDepth      = 4
+	sketchMaxCounter = 15 // 4 bits are enough, TinyLFU only compares "popular vs not"
+)
+
+// frequencySketch is a count-min sketch used by TinyLFUCache to remember approximate
+// access frequencies of keys, including keys which are not (or no longer) in the cache.
+//
+// Each key maps to one counter in each of sketchDepth rows, the estimate is the minimum of them:
+// collisions can only inflate a counter, so the minimum is the least polluted one.
+//
+// To keep history fresh, after sampleSize increments every counter is halved ("reset" in the TinyLFU paper),
+// so yesterday's hot keys slowly fade away instead of staying hot forever.
+type frequencySketch[K comparable] struct {
+	seed       maphash.Seed
+	rows       [sketchDepth][]uint8
+	mask       uint64
+	additions  int
+	sampleSize int
+}
+
+// Time: O(width)
+// Space: O(width * depth), width = next power of two >= capacity
+func newFrequencySketch[K comparable](capacity int) *frequencySketch[K] {
+	width := 1
+	for width < capacity {
+		width <<= 1
+	}
+
+	s := &frequencySketch[K]{
+		seed:       maphash.MakeSeed(),
+		mask:       uint64(width - 1),
+		sampleSize: 10 * max(capacity, 1),
+	}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+	return s
+}
+
+// Every row gets its own index: the key's hash is re-mixed with a row-specific offset (splitmix64 finalizer).
+// Plain h1 + i*h2 is not enough here: with a narrow table only the low bits of h2 matter,
+// so two keys colliding in one row would too often collide in all of them.
+// Time: O(1)
+// Space: O(1)
+func (s *frequencySketch[K]) indexes(key K) [sketchDepth]uint64 {
+	h := maphash.Comparable(s.seed, key)
+
+	var idx [sketchDepth]uint64
+	for i := range idx {
+		idx[i] = mix64(h+uint64(i)*0x9e3779b97f4a7c15) & s.mask
+	}
+	return idx
+}
+
+// Time: O(1)
+// Space: O(1)
+func mix64(h uint64) uint64 {
+	h ^= h >> 30
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	h ^= h >> 31
+	return h
+}
+
+// Time: O(depth), amortized O(1) with occasional O(width * depth) reset
+// Space: O(1)
+func (s *frequencySketch[K]) increment(key K) {
+	for i, idx := range s.indexes(key) {
+		if s.rows[i][idx] < sketchMaxCounter {
+			s.rows[i][idx]++
+		}
+	}
+
+	s.additions++
+	if s.additions >= s.sampleSize {
+		s.reset()
+	}
+}
+
+// Time: O(depth)
+// Space: O(1)
+func (s *frequencySketch[K]) estimate(key K) int {
+	minCount := uint8(sketchMaxCounter)
+	for i, idx := range s.indexes(key) {
+		minCount = min(minCount, s.rows[i][idx])
+	}
+	return int(minCount)
+}
+
+// Time: O(width * depth)
+// Space: O(1)
+func (s *frequencySketch[K]) reset() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] >>= 1
+		}
+	}
+	s.additions /= 2
+}
@@ -0,0 +1,71 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestFrequencySketch_WidthIsPowerOfTwo(t *testing.T) {
+	s := newFrequencySketch[string](100)
+	if len(s.rows[0]) != 128 {
+		t.Errorf("width is %d, expected 128", len(s.rows[0]))
+	}
+	if s.mask != 127 {
+		t.Errorf("mask is %d, expected 127", s.mask)
+	}
+}
+
+func TestFrequencySketch_Estimate(t *testing.T) {
+	s := newFrequencySketch[string](1000)
+	for range 5 {
+		s.increment("hot")
+	}
+	s.increment("cold")
+
+	if est := s.estimate("hot"); est < 5 {
+		t.Errorf("estimate(hot) is %d, expected at least 5 (count-min never underestimates)", est)
+	}
+	if est := s.estimate("cold"); est < 1 {
+		t.Errorf("estimate(cold) is %d, expected at least 1", est)
+	}
+	if s.estimate("hot") <= s.estimate("cold") {
+		t.Error("hot key should be estimated as more frequent than cold key")
+	}
+}
+
+func TestFrequencySketch_CounterSaturates(t *testing.T) {
+	s := newFrequencySketch[string](1000)
+	for range 100 {
+		s.increment("key")
+	}
+	if est := s.estimate("key"); est != sketchMaxCounter {
+		t.Errorf("estimate is %d, expected saturation at %d", est, sketchMaxCounter)
+	}
+}
+
+func TestFrequencySketch_ResetHalvesCounters(t *testing.T) {
+	s := newFrequencySketch[string](1000)
+	for range 8 {
+		s.increment("key")
+	}
+
+	s.reset()
+
+	if est := s.estimate("key"); est != 4 {
+		t.Errorf("estimate is %d after reset, expected 4", est)
+	}
+	if s.additions != 4 {
+		t.Errorf("additions is %d after reset, expected 4", s.additions)
+	}
+}
+
+func TestFrequencySketch_ResetsAfterSampleSize(t *testing.T) {
+	s := newFrequencySketch[string](16)
+	for i := range s.sampleSize {
+		s.increment(fmt.Sprintf("key_%d", i))
+	}
+
+	if s.additions != s.sampleSize/2 {
+		t.Errorf("additions is %d, expected %d after automatic reset", s.additions, s.sampleSize/2)
+	}
+}
@@ -1,6 +1,15 @@
 package cache
 
-import "container/list"
+import (
+	"container/list"
+	"errors"
+	"time"
+)
+
+var (
+	ErrCostExceedsBudget = errors.New("item cost exceeds cache cost budget")
+	ErrNegativeCost      = errors.New("item cost must not be negative")
+)
 
 // Why I wrote an LFU Cache here with Doubly Linked Lists instead of using hash table with linear probing (as Labaratory task were suggesting)?
 //
@@ -37,51 +46,107 @@ import "container/list"
 // But for sure there is also a trade-off, and this approach uses much more memory (linked list nodes and pointers, two maps).
 // And even so, for most of mediocre business applications such tradeoff is justified by great performance for user experience.
 
-type cacheEntry struct {
-	key   string
-	value any
-	freq  int
+type cacheEntry[K comparable, V any] struct {
+	key       K
+	value     V
+	freq      int
+	expiresAt time.Time // zero means the entry never expires
+	cost      int64
 }
 
-type LFUCache struct {
-	capacity int
-	minFreq  int
-	cache    map[string]*list.Element
-	freqMap  map[int]*list.List
+// Time: O(1)
+// Space: O(1)
+func (e *cacheEntry[K, V]) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
+
+// LFUCache is generic over keys and values, so there is no need in type assertions on Get.
+// NewLFUCache below is kept for the old string -> any flavour.
+type LFUCache[K comparable, V any] struct {
+	capacity   int
+	minFreq    int
+	cache      map[K]*list.Element
+	freqMap    map[int]*list.List
+	defaultTTL time.Duration
+	clock      Clock
+	stats      Stats
+	onEvict    func(key K, value V, reason EvictReason)
+
+	// frequency aging, see aging.go
+	aging         AgingMode
+	halvingPeriod int
+	ops           int
+	age           int
+
+	// cost budget, see PutWithCost
+	maxCost   int64
+	totalCost int64
 }
 
 // Time: O(1)
 // Space: O(1) initialization, O(capacity) when full
-func NewLFUCache(capacity int) *LFUCache {
-	return &LFUCache{
-		capacity: capacity,
-		minFreq:  0,
-		cache:    make(map[string]*list.Element),
-		freqMap:  make(map[int]*list.List),
+func NewLFU[K comparable, V any](capacity int, opts ...Option) *LFUCache[K, V] {
+	o := newOptions(opts)
+	return &LFUCache[K, V]{
+		capacity:      capacity,
+		minFreq:       0,
+		cache:         make(map[K]*list.Element),
+		freqMap:       make(map[int]*list.List),
+		defaultTTL:    o.defaultTTL,
+		clock:         o.clock,
+		aging:         o.aging,
+		halvingPeriod: o.halvingPeriod,
+		maxCost:       o.maxCost,
 	}
 }
 
+// NewLFUCache is a thin alias for NewLFU[string, any], the original (pre-generic) flavour of the cache.
+// Time: O(1)
+// Space: O(1) initialization, O(capacity) when full
+func NewLFUCache(capacity int, opts ...Option) *LFUCache[string, any] {
+	return NewLFU[string, any](capacity, opts...)
+}
+
+// Expired entries are dropped lazily here, so Get never returns a stale value.
 // Time: O(1) - map lookup + incrementFreq (both O(1))
 // Space: O(1)
-func (c *LFUCache) Get(key string) (any, bool) {
+func (c *LFUCache[K, V]) Get(key K) (V, bool) {
 	item, ok := c.cache[key]
 	if !ok {
-		return nil, false
+		c.stats.Misses++
+		var zero V
+		return zero, false
 	}
 
+	if item.Value.(*cacheEntry[K, V]).expired(c.clock()) {
+		c.removeElement(item)
+		c.stats.Misses++
+		c.stats.Evictions++
+		c.notifyEvict(item, EvictExpired)
+		var zero V
+		return zero, false
+	}
+
+	c.stats.Hits++
 	c.incrementFreq(item)
-	return item.Value.(*cacheEntry).value, true
+	value := item.Value.(*cacheEntry[K, V]).value
+	c.tickAging()
+	return value, true
 }
 
 // Time: O(1) - list removal and insertion are O(1) for doubly linked list
 // Space: O(1)
-func (c *LFUCache) incrementFreq(listNode *list.Element) {
-	currentElement := listNode.Value.(*cacheEntry)
+func (c *LFUCache[K, V]) incrementFreq(listNode *list.Element) {
+	currentElement := listNode.Value.(*cacheEntry[K, V])
 	prevFreq := currentElement.freq
 
 	c.freqMap[prevFreq].Remove(listNode)
-	if c.freqMap[prevFreq].Len() == 0 && c.minFreq == prevFreq {
-		c.minFreq++
+	if c.freqMap[prevFreq].Len() == 0 {
+		// with dynamic aging frequencies only grow, empty buckets left behind would pile up forever
+		delete(c.freqMap, prevFreq)
+		if c.minFreq == prevFreq {
+			c.minFreq++
+		}
 	}
 
 	currentElement.freq++
@@ -92,30 +157,125 @@ func (c *LFUCache) incrementFreq(listNode *list.Element) {
 	c.cache[currentElement.key] = newElem
 }
 
+// Put stores value with the default TTL (see WithDefaultTTL).
 // Time: O(1) - map lookup, possible evict O(1), list insertion O(1)
 // Space: O(1) per call, O(capacity) total for stored entries
-func (c *LFUCache) Put(key string, value any) {
+func (c *LFUCache[K, V]) Put(key K, value V) {
+	c.PutWithTTL(key, value, c.defaultTTL)
+}
+
+// PutWithTTL stores value that expires after ttl. ttl <= 0 means it never expires.
+// Updating an existing key also resets its deadline.
+// Time: O(1), same as Put
+// Space: O(1)
+func (c *LFUCache[K, V]) PutWithTTL(key K, value V, ttl time.Duration) {
+	// cost 1 always fits into a budget, so put can't fail here
+	_ = c.put(key, value, ttl, 1)
+}
+
+// PutWithCost stores value which weighs `cost` units of the WithMaxCost budget (bytes, for example).
+// Victims are popped from the minFreq bucket one by one until the new item fits.
+// An item heavier than the whole budget is rejected with ErrCostExceedsBudget instead of flushing the cache.
+// Put and PutWithTTL count every item as cost 1.
+// Time: O(1) per evicted victim (plus O(F) each time a minFreq bucket is emptied by the loop)
+// Space: O(1)
+func (c *LFUCache[K, V]) PutWithCost(key K, value V, cost int64) error {
+	return c.put(key, value, c.defaultTTL, cost)
+}
+
+// Time: O(1), see PutWithCost for the multi-eviction case
+// Space: O(1)
+func (c *LFUCache[K, V]) put(key K, value V, ttl time.Duration, cost int64) error {
 	if c.capacity <= 0 {
-		return
+		return nil
+	}
+	if cost < 0 {
+		return ErrNegativeCost
+	}
+	if c.maxCost > 0 && cost > c.maxCost {
+		return ErrCostExceedsBudget
+	}
+
+	now := c.clock()
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = now.Add(ttl)
 	}
 
+	relinkFreq := 0
 	if item, ok := c.cache[key]; ok {
-		item.Value.(*cacheEntry).value = value
-		c.incrementFreq(item)
-		return
+		entry := item.Value.(*cacheEntry[K, V])
+		switch {
+		case entry.expired(now):
+			// stale entry must not pass its frequency to the new value
+			c.removeElement(item)
+			c.stats.Evictions++
+			c.notifyEvict(item, EvictExpired)
+
+		case c.maxCost <= 0 || c.totalCost-entry.cost+cost <= c.maxCost:
+			c.notifyEvict(item, EvictReplaced)
+			c.totalCost += cost - entry.cost
+			entry.value, entry.expiresAt, entry.cost = value, expiresAt, cost
+			c.incrementFreq(item)
+			c.tickAging()
+			return nil
+
+		default:
+			// the new cost does not fit: take the entry out first so it can't become its own victim,
+			// make room and put it back one frequency higher
+			c.notifyEvict(item, EvictReplaced)
+			c.removeElement(item)
+			relinkFreq = entry.freq + 1
+		}
 	}
 
-	if len(c.cache) >= c.capacity {
+	c.makeRoom(cost)
+
+	// without dynamic aging age is always 0 and new entries start from freq 1 as usual;
+	// with it age may be raised by makeRoom, so it is read only now
+	freq := c.age + 1
+	if relinkFreq > 0 {
+		freq = relinkFreq
+	}
+	c.link(&cacheEntry[K, V]{key: key, value: value, freq: freq, expiresAt: expiresAt, cost: cost})
+	c.tickAging()
+	return nil
+}
+
+// makeRoom evicts minFreq victims until one more entry of given cost fits both capacity and cost budget.
+// Time: O(1) per victim, O(F) extra when a victim empties the minFreq bucket and the loop continues
+// Space: O(1)
+func (c *LFUCache[K, V]) makeRoom(cost int64) {
+	for len(c.cache) > 0 && (len(c.cache) >= c.capacity || (c.maxCost > 0 && c.totalCost+cost > c.maxCost)) {
+		if c.minFreq == 0 {
+			c.recomputeMinFreq()
+		}
 		c.evict()
 	}
+}
+
+// link puts the entry into its frequency bucket and keeps minFreq pointing at the lowest bucket.
+//
+// After evict() minFreq may be unknown (0) while the cache is not empty. That happens only when
+// the victim's bucket became empty, so every remaining entry has freq > victim's freq >= age,
+// and a fresh entry with freq = age + 1 is the minimum anyway. Only a re-linked entry
+// with a higher freq needs the O(F) search.
+// Time: O(1), O(F) for re-linked entries after eviction emptied the minFreq bucket
+// Space: O(1)
+func (c *LFUCache[K, V]) link(entry *cacheEntry[K, V]) {
+	if c.minFreq == 0 && len(c.cache) > 0 && entry.freq > c.age+1 {
+		c.recomputeMinFreq()
+	}
+
+	if c.freqMap[entry.freq] == nil {
+		c.freqMap[entry.freq] = list.New()
+	}
+	c.cache[entry.key] = c.freqMap[entry.freq].PushFront(entry)
+	c.totalCost += entry.cost
 
-	entry := &cacheEntry{key: key, value: value, freq: 1}
-	if c.freqMap[1] == nil {
-		c.freqMap[1] = list.New()
+	if c.minFreq == 0 || entry.freq < c.minFreq {
+		c.minFreq = entry.freq
 	}
-	elem := c.freqMap[1].PushFront(entry)
-	c.cache[key] = elem
-	c.minFreq = 1
 }
 
 // Time: O(1) - minFreq gives direct access to eviction candidate bucket,
@@ -123,11 +283,140 @@ func (c *LFUCache) Put(key string, value any) {
 //	Back() and Remove() are O(1) for doubly linked list
 //
 // Space: O(1)
-func (c *LFUCache) evict() {
+func (c *LFUCache[K, V]) evict() {
 	bucket := c.freqMap[c.minFreq]
 	elem := bucket.Back()
-	e := elem.Value.(*cacheEntry)
+	e := elem.Value.(*cacheEntry[K, V])
 
 	bucket.Remove(elem)
 	delete(c.cache, e.key)
+	c.totalCost -= e.cost
+	if bucket.Len() == 0 {
+		// next minimum is unknown here, link() or makeRoom() will figure it out
+		delete(c.freqMap, c.minFreq)
+		c.minFreq = 0
+	}
+	if c.aging == AgingDynamic {
+		c.age = e.freq
+	}
+	c.stats.Evictions++
+	c.notifyEvict(elem, EvictCapacity)
+}
+
+// victim peeks at the entry evict would remove next, without removing it.
+// Time: O(1)
+// Space: O(1)
+func (c *LFUCache[K, V]) victim() (*cacheEntry[K, V], bool) {
+	if c.minFreq == 0 && len(c.cache) > 0 {
+		c.recomputeMinFreq()
+	}
+	bucket := c.freqMap[c.minFreq]
+	if bucket == nil || bucket.Len() == 0 {
+		return nil, false
+	}
+	return bucket.Back().Value.(*cacheEntry[K, V]), true
+}
+
+// Delete removes key from the cache, reports whether it was there.
+// Time: O(1), O(F) when minFreq bucket becomes empty (see removeElement)
+// Space: O(1)
+func (c *LFUCache[K, V]) Delete(key K) bool {
+	item, ok := c.cache[key]
+	if !ok {
+		return false
+	}
+
+	c.removeElement(item)
+	c.notifyEvict(item, EvictDeleted)
+	return true
+}
+
+// OnEvict registers a callback which is called every time an entry leaves the cache
+// (or its value is replaced by Put). It runs synchronously, inside the cache operation,
+// so it should be cheap and must not call back into the same cache.
+// Time: O(1)
+// Space: O(1)
+func (c *LFUCache[K, V]) OnEvict(fn func(key K, value V, reason EvictReason)) {
+	c.onEvict = fn
+}
+
+// Time: O(1) + callback
+// Space: O(1)
+func (c *LFUCache[K, V]) notifyEvict(elem *list.Element, reason EvictReason) {
+	if c.onEvict == nil {
+		return
+	}
+	e := elem.Value.(*cacheEntry[K, V])
+	c.onEvict(e.key, e.value, reason)
+}
+
+// Cost returns the summary cost of stored entries.
+// Time: O(1)
+// Space: O(1)
+func (c *LFUCache[K, V]) Cost() int64 {
+	return c.totalCost
+}
+
+// Len returns number of stored entries. Expired entries which were not yet
+// touched by Get or PurgeExpired are still counted.
+// Time: O(1)
+// Space: O(1)
+func (c *LFUCache[K, V]) Len() int {
+	return len(c.cache)
+}
+
+// Time: O(1)
+// Space: O(1)
+func (c *LFUCache[K, V]) Stats() Stats {
+	return c.stats
+}
+
+// PurgeExpired sweeps the whole cache and drops every expired entry.
+// Returns how many entries were removed.
+// Time: O(n) - every entry is checked
+// Space: O(1)
+func (c *LFUCache[K, V]) PurgeExpired() int {
+	now := c.clock()
+	removed := 0
+	for _, elem := range c.cache {
+		if elem.Value.(*cacheEntry[K, V]).expired(now) {
+			c.removeElement(elem)
+			c.stats.Evictions++
+			c.notifyEvict(elem, EvictExpired)
+			removed++
+		}
+	}
+	return removed
+}
+
+// removeElement unlinks an arbitrary entry (not necessarily the minFreq victim).
+// When it empties the minFreq bucket, minFreq has to be searched again:
+// unlike in incrementFreq, the entry does not move to minFreq+1, it just disappears.
+// Time: O(1), O(F) when minFreq bucket becomes empty, where F = number of distinct frequencies
+// Space: O(1)
+func (c *LFUCache[K, V]) removeElement(elem *list.Element) {
+	e := elem.Value.(*cacheEntry[K, V])
+	bucket := c.freqMap[e.freq]
+
+	bucket.Remove(elem)
+	delete(c.cache, e.key)
+	c.totalCost -= e.cost
+
+	if bucket.Len() == 0 {
+		delete(c.freqMap, e.freq)
+		if c.minFreq == e.freq {
+			c.recomputeMinFreq()
+		}
+	}
+}
+
+// Time: O(F) where F = number of distinct frequencies
+// Space: O(1)
+func (c *LFUCache[K, V]) recomputeMinFreq() {
+	c.minFreq = 0
+	for freq, bucket := range c.freqMap {
+		if bucket.Len() > 0 && (c.minFreq == 0 || freq < c.minFreq) {
+			c.minFreq = freq
+		}
+	}
 }
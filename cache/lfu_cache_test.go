@@ -1,7 +1,9 @@
 package cache
 
 import (
+	"errors"
 	"testing"
+	"time"
 )
 
 func TestNewLFUCache(t *testing.T) {
@@ -111,7 +113,7 @@ func TestGet_IncrementsFrequency(t *testing.T) {
 	c.Get("key")
 
 	elem := c.cache["key"]
-	entry := elem.Value.(*cacheEntry)
+	entry := elem.Value.(*cacheEntry[string, any])
 	if entry.freq != 4 {
 		t.Errorf("freq is %d, expected 4 (1 from Put + 3 from Get)", entry.freq)
 	}
@@ -319,9 +321,9 @@ func TestFrequencyBucketsCorrectness(t *testing.T) {
 	c.Get("a")
 	c.Get("b")
 
-	entryA := c.cache["a"].Value.(*cacheEntry)
-	entryB := c.cache["b"].Value.(*cacheEntry)
-	entryC := c.cache["c"].Value.(*cacheEntry)
+	entryA := c.cache["a"].Value.(*cacheEntry[string, any])
+	entryB := c.cache["b"].Value.(*cacheEntry[string, any])
+	entryC := c.cache["c"].Value.(*cacheEntry[string, any])
 
 	if entryA.freq != 3 {
 		t.Errorf("a.freq is %d, expected 3", entryA.freq)
@@ -333,3 +335,533 @@ func TestFrequencyBucketsCorrectness(t *testing.T) {
 		t.Errorf("c.freq is %d, expected 1", entryC.freq)
 	}
 }
+
+func TestNewLFU_TypedKeysAndValues(t *testing.T) {
+	type userID struct {
+		tenant int
+		id     int
+	}
+
+	c := NewLFU[userID, string](2)
+	c.Put(userID{1, 1}, "alice")
+	c.Put(userID{1, 2}, "bob")
+
+	name, ok := c.Get(userID{1, 1})
+	if !ok {
+		t.Fatal("{1,1} should exist")
+	}
+	if name != "alice" {
+		t.Errorf("Get({1,1}) = %q, expected alice", name)
+	}
+
+	c.Put(userID{2, 1}, "carol")
+	if _, ok := c.Get(userID{1, 2}); ok {
+		t.Error("{1,2} should be evicted (least frequently used)")
+	}
+}
+
+func TestNewLFU_MissReturnsZeroValue(t *testing.T) {
+	c := NewLFU[int, int](2)
+	c.Put(1, 10)
+
+	val, ok := c.Get(2)
+	if ok {
+		t.Error("Get should return false for missing key")
+	}
+	if val != 0 {
+		t.Errorf("Get returned %d, expected zero value", val)
+	}
+}
+
+// fakeClock is a manually driven Clock for TTL tests.
+type fakeClock struct {
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (fc *fakeClock) Now() time.Time {
+	return fc.now
+}
+
+func (fc *fakeClock) Advance(d time.Duration) {
+	fc.now = fc.now.Add(d)
+}
+
+func TestPutWithTTL_ExpiresOnGet(t *testing.T) {
+	clock := newFakeClock()
+	c := NewLFU[string, int](10, WithClock(clock.Now))
+
+	c.PutWithTTL("session", 1, time.Minute)
+
+	clock.Advance(59 * time.Second)
+	if _, ok := c.Get("session"); !ok {
+		t.Error("session should exist before deadline")
+	}
+
+	clock.Advance(time.Second)
+	if _, ok := c.Get("session"); ok {
+		t.Error("session should expire exactly at deadline")
+	}
+	if _, ok := c.cache["session"]; ok {
+		t.Error("expired entry should be removed from cache map on Get")
+	}
+}
+
+func TestPutWithTTL_ZeroNeverExpires(t *testing.T) {
+	clock := newFakeClock()
+	c := NewLFU[string, int](10, WithClock(clock.Now))
+
+	c.PutWithTTL("forever", 1, 0)
+	clock.Advance(24 * 365 * time.Hour)
+
+	if _, ok := c.Get("forever"); !ok {
+		t.Error("entry with zero TTL should never expire")
+	}
+}
+
+func TestDefaultTTL_AppliesToPut(t *testing.T) {
+	clock := newFakeClock()
+	c := NewLFU[string, int](10, WithClock(clock.Now), WithDefaultTTL(time.Second))
+
+	c.Put("a", 1)
+	clock.Advance(2 * time.Second)
+
+	if _, ok := c.Get("a"); ok {
+		t.Error("a should expire after default TTL")
+	}
+}
+
+func TestPutWithTTL_UpdateResetsDeadline(t *testing.T) {
+	clock := newFakeClock()
+	c := NewLFU[string, int](10, WithClock(clock.Now))
+
+	c.PutWithTTL("a", 1, time.Minute)
+	clock.Advance(50 * time.Second)
+	c.PutWithTTL("a", 2, time.Minute)
+	clock.Advance(50 * time.Second)
+
+	val, ok := c.Get("a")
+	if !ok {
+		t.Fatal("a should exist, deadline was reset by second Put")
+	}
+	if val != 2 {
+		t.Errorf("Get(a) = %d, expected 2", val)
+	}
+}
+
+func TestPutWithTTL_ExpiredKeyStartsFresh(t *testing.T) {
+	clock := newFakeClock()
+	c := NewLFU[string, int](10, WithClock(clock.Now))
+
+	c.PutWithTTL("a", 1, time.Second)
+	c.Get("a")
+	c.Get("a")
+	clock.Advance(time.Second)
+
+	c.Put("a", 2)
+	if freq := c.cache["a"].Value.(*cacheEntry[string, int]).freq; freq != 1 {
+		t.Errorf("freq is %d, expected 1 for re-inserted expired key", freq)
+	}
+}
+
+func TestPurgeExpired(t *testing.T) {
+	clock := newFakeClock()
+	c := NewLFU[string, int](10, WithClock(clock.Now))
+
+	c.PutWithTTL("short1", 1, time.Second)
+	c.PutWithTTL("short2", 2, time.Second)
+	c.PutWithTTL("long", 3, time.Hour)
+	c.Put("forever", 4)
+
+	clock.Advance(time.Minute)
+
+	if removed := c.PurgeExpired(); removed != 2 {
+		t.Errorf("PurgeExpired removed %d, expected 2", removed)
+	}
+	if len(c.cache) != 2 {
+		t.Errorf("cache has %d entries, expected 2", len(c.cache))
+	}
+	for _, key := range []string{"long", "forever"} {
+		if _, ok := c.Get(key); !ok {
+			t.Errorf("%s should survive PurgeExpired", key)
+		}
+	}
+}
+
+func TestExpiry_KeepsMinFreqInvariant(t *testing.T) {
+	clock := newFakeClock()
+	c := NewLFU[string, int](3, WithClock(clock.Now))
+
+	c.PutWithTTL("cold", 1, time.Second)
+	c.Put("warm", 2)
+	c.Put("hot", 3)
+	c.Get("warm")
+	c.Get("hot")
+	c.Get("hot")
+
+	if c.minFreq != 1 {
+		t.Fatalf("minFreq is %d, expected 1", c.minFreq)
+	}
+
+	clock.Advance(time.Second)
+	c.PurgeExpired()
+
+	if c.minFreq != 2 {
+		t.Errorf("minFreq is %d, expected 2 after the only freq-1 entry expired", c.minFreq)
+	}
+	if _, ok := c.freqMap[1]; ok {
+		t.Error("empty freq-1 bucket should be dropped from freqMap")
+	}
+
+	c.Put("new1", 4)
+	c.Put("new2", 5)
+
+	if _, ok := c.Get("warm"); !ok {
+		t.Error("warm should survive, new1 was the least frequently used victim")
+	}
+	if _, ok := c.Get("new1"); ok {
+		t.Error("new1 should be evicted")
+	}
+}
+
+func TestExpiry_LastEntryResetsMinFreq(t *testing.T) {
+	clock := newFakeClock()
+	c := NewLFU[string, int](1, WithClock(clock.Now))
+
+	c.PutWithTTL("a", 1, time.Second)
+	c.Get("a")
+	clock.Advance(time.Second)
+	c.Get("a")
+
+	if c.minFreq != 0 {
+		t.Errorf("minFreq is %d, expected 0 for empty cache", c.minFreq)
+	}
+
+	c.Put("b", 2)
+	if _, ok := c.Get("b"); !ok {
+		t.Error("b should exist")
+	}
+}
+
+func TestStats_CountsHitsMissesEvictions(t *testing.T) {
+	clock := newFakeClock()
+	c := NewLFU[string, int](2, WithClock(clock.Now))
+
+	c.Put("a", 1)
+	c.PutWithTTL("b", 2, time.Second)
+	c.Get("a")
+	c.Get("missing")
+
+	clock.Advance(time.Second)
+	c.Get("b")
+
+	c.Put("c", 3)
+	c.Put("d", 4)
+
+	stats := c.Stats()
+	if stats.Hits != 1 {
+		t.Errorf("Hits is %d, expected 1", stats.Hits)
+	}
+	if stats.Misses != 2 {
+		t.Errorf("Misses is %d, expected 2 (missing key + expired key)", stats.Misses)
+	}
+	if stats.Evictions != 2 {
+		t.Errorf("Evictions is %d, expected 2 (expiry + capacity)", stats.Evictions)
+	}
+	if c.Len() != 2 {
+		t.Errorf("Len is %d, expected 2", c.Len())
+	}
+}
+
+type evictEvent struct {
+	key    string
+	value  int
+	reason EvictReason
+}
+
+func recordEvictions(c *LFUCache[string, int]) *[]evictEvent {
+	events := &[]evictEvent{}
+	c.OnEvict(func(key string, value int, reason EvictReason) {
+		*events = append(*events, evictEvent{key, value, reason})
+	})
+	return events
+}
+
+func TestOnEvict_Capacity(t *testing.T) {
+	c := NewLFU[string, int](2)
+	events := recordEvictions(c)
+
+	c.Put("a", 1)
+	c.Put("b", 2)
+	c.Get("b")
+	c.Put("c", 3)
+
+	expected := []evictEvent{{"a", 1, EvictCapacity}}
+	if len(*events) != 1 || (*events)[0] != expected[0] {
+		t.Errorf("events = %v, expected %v", *events, expected)
+	}
+}
+
+func TestOnEvict_Delete(t *testing.T) {
+	c := NewLFU[string, int](2)
+	events := recordEvictions(c)
+
+	c.Put("a", 1)
+	if !c.Delete("a") {
+		t.Error("Delete should return true for existing key")
+	}
+	if c.Delete("a") {
+		t.Error("Delete should return false for missing key")
+	}
+	if _, ok := c.Get("a"); ok {
+		t.Error("a should not exist after Delete")
+	}
+
+	expected := evictEvent{"a", 1, EvictDeleted}
+	if len(*events) != 1 || (*events)[0] != expected {
+		t.Errorf("events = %v, expected [%v]", *events, expected)
+	}
+	if c.Stats().Evictions != 0 {
+		t.Errorf("Evictions is %d, explicit delete should not count as eviction", c.Stats().Evictions)
+	}
+}
+
+func TestOnEvict_Expired(t *testing.T) {
+	clock := newFakeClock()
+	c := NewLFU[string, int](10, WithClock(clock.Now))
+	events := recordEvictions(c)
+
+	c.PutWithTTL("lazy", 1, time.Second)
+	c.PutWithTTL("swept", 2, time.Second)
+	clock.Advance(time.Second)
+
+	c.Get("lazy")
+	c.PurgeExpired()
+
+	if len(*events) != 2 {
+		t.Fatalf("got %d events, expected 2: %v", len(*events), *events)
+	}
+	for _, e := range *events {
+		if e.reason != EvictExpired {
+			t.Errorf("%q evicted with reason %v, expected expired", e.key, e.reason)
+		}
+	}
+}
+
+func TestOnEvict_Replaced(t *testing.T) {
+	c := NewLFU[string, int](10)
+	events := recordEvictions(c)
+
+	c.Put("a", 1)
+	c.Put("a", 2)
+
+	expected := evictEvent{"a", 1, EvictReplaced}
+	if len(*events) != 1 || (*events)[0] != expected {
+		t.Errorf("events = %v, expected [%v]", *events, expected)
+	}
+}
+
+func TestDelete_KeepsMinFreqInvariant(t *testing.T) {
+	c := NewLFU[string, int](2)
+	c.Put("a", 1)
+	c.Put("b", 2)
+	c.Get("b")
+
+	c.Delete("a")
+	if c.minFreq != 2 {
+		t.Errorf("minFreq is %d, expected 2", c.minFreq)
+	}
+}
+
+func TestStats_HitRatio(t *testing.T) {
+	if ratio := (Stats{}).HitRatio(); ratio != 0 {
+		t.Errorf("HitRatio of empty stats is %v, expected 0", ratio)
+	}
+
+	c := NewLFU[string, int](10)
+	c.Put("a", 1)
+	c.Get("a")
+	c.Get("a")
+	c.Get("a")
+	c.Get("missing")
+
+	if ratio := c.Stats().HitRatio(); ratio != 0.75 {
+		t.Errorf("HitRatio is %v, expected 0.75", ratio)
+	}
+}
+
+func TestEvictReason_String(t *testing.T) {
+	tests := map[EvictReason]string{
+		EvictCapacity:   "capacity",
+		EvictDeleted:    "deleted",
+		EvictExpired:    "expired",
+		EvictReplaced:   "replaced",
+		EvictReason(42): "unknown",
+	}
+	for reason, expected := range tests {
+		if reason.String() != expected {
+			t.Errorf("String() = %q, expected %q", reason.String(), expected)
+		}
+	}
+}
+
+func TestPutWithCost_EvictsUntilFits(t *testing.T) {
+	c := NewLFU[string, int](100, WithMaxCost(100))
+	events := recordEvictions(c)
+
+	c.PutWithCost("small1", 1, 20)
+	c.PutWithCost("small2", 2, 20)
+	c.PutWithCost("small3", 3, 20)
+	c.PutWithCost("hot", 4, 30)
+	c.Get("hot")
+
+	if err := c.PutWithCost("big", 5, 60); err != nil {
+		t.Fatalf("PutWithCost returned %v", err)
+	}
+
+	// 90 + 60 = 150, so 50 must go: small1, small2, small3 are the LFU victims in LRU order
+	for _, key := range []string{"small1", "small2", "small3"} {
+		if _, ok := c.cache[key]; ok {
+			t.Errorf("%s should be evicted to make room", key)
+		}
+	}
+	if _, ok := c.cache["hot"]; !ok {
+		t.Error("hot should survive, it has higher frequency")
+	}
+	if c.Cost() != 90 {
+		t.Errorf("Cost is %d, expected 90", c.Cost())
+	}
+	if len(*events) != 3 {
+		t.Errorf("got %d eviction events, expected 3", len(*events))
+	}
+}
+
+func TestPutWithCost_StopsAsSoonAsItFits(t *testing.T) {
+	c := NewLFU[string, int](100, WithMaxCost(100))
+	c.PutWithCost("a", 1, 40)
+	c.PutWithCost("b", 2, 40)
+	c.PutWithCost("c", 3, 50)
+
+	if _, ok := c.cache["a"]; ok {
+		t.Error("a should be evicted")
+	}
+	if _, ok := c.cache["b"]; !ok {
+		t.Error("b should stay, one eviction was enough")
+	}
+}
+
+func TestPutWithCost_RejectsItemLargerThanBudget(t *testing.T) {
+	c := NewLFU[string, int](100, WithMaxCost(100))
+	c.PutWithCost("a", 1, 50)
+	c.PutWithCost("b", 2, 50)
+
+	err := c.PutWithCost("huge", 3, 101)
+	if !errors.Is(err, ErrCostExceedsBudget) {
+		t.Errorf("PutWithCost returned %v, expected ErrCostExceedsBudget", err)
+	}
+	if c.Len() != 2 || c.Cost() != 100 {
+		t.Errorf("Len/Cost is %d/%d, rejected item must not evict anything", c.Len(), c.Cost())
+	}
+}
+
+func TestPutWithCost_NegativeCost(t *testing.T) {
+	c := NewLFU[string, int](10, WithMaxCost(100))
+	if err := c.PutWithCost("a", 1, -1); !errors.Is(err, ErrNegativeCost) {
+		t.Errorf("PutWithCost returned %v, expected ErrNegativeCost", err)
+	}
+}
+
+func TestPutWithCost_UpdateGrowsCost(t *testing.T) {
+	c := NewLFU[string, int](10, WithMaxCost(100))
+	c.PutWithCost("a", 1, 30)
+	c.PutWithCost("b", 2, 30)
+	c.PutWithCost("c", 3, 30)
+
+	// a is the LFU victim, but it is the key being updated - it must not evict itself
+	if err := c.PutWithCost("a", 10, 60); err != nil {
+		t.Fatalf("PutWithCost returned %v", err)
+	}
+
+	val, ok := c.Get("a")
+	if !ok || val != 10 {
+		t.Errorf("Get(a) = %d, %v, expected 10, true", val, ok)
+	}
+	if freq := c.cache["a"].Value.(*cacheEntry[string, int]).freq; freq != 3 {
+		t.Errorf("a.freq is %d, expected 3 (put, update, get)", freq)
+	}
+	if c.Cost() > 100 {
+		t.Errorf("Cost is %d, expected at most 100", c.Cost())
+	}
+	if _, ok := c.cache["b"]; ok {
+		t.Error("b should be evicted to fit the bigger a")
+	}
+}
+
+func TestPutWithCost_UpdateShrinksCost(t *testing.T) {
+	c := NewLFU[string, int](10, WithMaxCost(100))
+	c.PutWithCost("a", 1, 80)
+	c.PutWithCost("a", 2, 10)
+
+	if c.Cost() != 10 {
+		t.Errorf("Cost is %d, expected 10", c.Cost())
+	}
+}
+
+func TestPutWithCost_CostReleasedOnDeleteAndExpiry(t *testing.T) {
+	clock := newFakeClock()
+	c := NewLFU[string, int](10, WithMaxCost(100), WithClock(clock.Now), WithDefaultTTL(time.Second))
+	c.PutWithCost("a", 1, 40)
+	c.PutWithCost("b", 2, 40)
+
+	c.Delete("a")
+	if c.Cost() != 40 {
+		t.Errorf("Cost is %d after Delete, expected 40", c.Cost())
+	}
+
+	clock.Advance(time.Second)
+	c.PurgeExpired()
+	if c.Cost() != 0 {
+		t.Errorf("Cost is %d after expiry, expected 0", c.Cost())
+	}
+}
+
+func TestPutWithCost_WithoutBudget(t *testing.T) {
+	c := NewLFU[string, int](2)
+	c.PutWithCost("a", 1, 1_000_000)
+	c.PutWithCost("b", 2, 1_000_000)
+	c.PutWithCost("c", 3, 1_000_000)
+
+	if c.Len() != 2 {
+		t.Errorf("Len is %d, expected 2 (only entry capacity applies)", c.Len())
+	}
+}
+
+func TestPutWithCost_MinFreqValidAfterMultipleEvictions(t *testing.T) {
+	c := NewLFU[string, int](10, WithMaxCost(10))
+	c.PutWithCost("a", 1, 2)
+	c.PutWithCost("b", 2, 2)
+	c.Get("b")
+	c.PutWithCost("c", 3, 2)
+	c.Get("c")
+	c.Get("c")
+	c.PutWithCost("d", 4, 2)
+
+	if err := c.PutWithCost("e", 5, 7); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.minFreq != 1 {
+		t.Errorf("minFreq is %d, expected 1", c.minFreq)
+	}
+	for _, key := range []string{"a", "d", "b"} {
+		if _, ok := c.cache[key]; ok {
+			t.Errorf("%s should be evicted", key)
+		}
+	}
+	if _, ok := c.cache["c"]; !ok {
+		t.Error("c should survive")
+	}
+}
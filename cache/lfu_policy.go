@@ -0,0 +1,43 @@
+package cache
+
+// LFUPolicy is LFUCache's frequency-bucket design used as a Policy: the values are empty structs,
+// and the victim is peeked from the minFreq bucket before LFUCache.Put evicts it.
+type LFUPolicy[K comparable] struct {
+	lfu *LFUCache[K, struct{}]
+}
+
+// Time: O(1)
+// Space: O(capacity) when full
+func NewLFUPolicy[K comparable](capacity int) *LFUPolicy[K] {
+	return &LFUPolicy[K]{lfu: NewLFU[K, struct{}](capacity)}
+}
+
+// Time: O(1)
+// Space: O(1)
+func (p *LFUPolicy[K]) Hit(key K) {
+	p.lfu.Get(key)
+}
+
+// Time: O(1)
+// Space: O(1)
+func (p *LFUPolicy[K]) Insert(key K) (K, bool) {
+	if p.lfu.capacity <= 0 {
+		return key, true
+	}
+
+	var victim K
+	evicted := false
+	if p.lfu.Len() >= p.lfu.capacity {
+		if e, ok := p.lfu.victim(); ok {
+			victim, evicted = e.key, true
+		}
+	}
+	p.lfu.Put(key, struct{}{})
+	return victim, evicted
+}
+
+// Time: O(1), see LFUCache.Delete
+// Space: O(1)
+func (p *LFUPolicy[K]) Remove(key K) {
+	p.lfu.Delete(key)
+}
@@ -0,0 +1,36 @@
+package cache
+
+import "testing"
+
+func TestLFUPolicy_EvictsLeastFrequentlyUsed(t *testing.T) {
+	c := NewPolicyCache[string, int](NewLFUPolicy[string](3))
+	c.Put("a", 1)
+	c.Put("b", 2)
+	c.Put("c", 3)
+	c.Get("a")
+	c.Get("a")
+	c.Get("c")
+	c.Put("d", 4)
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("b should be evicted (least frequently used)")
+	}
+	if c.Len() != 3 {
+		t.Errorf("Len is %d, expected 3", c.Len())
+	}
+}
+
+func TestLFUPolicy_VictimMatchesLFUCache(t *testing.T) {
+	policy := NewLFUPolicy[string](2)
+	policy.Insert("a")
+	policy.Insert("b")
+	policy.Hit("a")
+
+	victim, evicted := policy.Insert("c")
+	if !evicted || victim != "b" {
+		t.Errorf("Insert returned (%q, %v), expected (b, true)", victim, evicted)
+	}
+	if _, ok := policy.lfu.cache["b"]; ok {
+		t.Error("victim reported by policy should be gone from the underlying LFUCache")
+	}
+}
@@ -0,0 +1,209 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrLoaderPanicked is what callers waiting for a load get when the loader panicked.
+// The caller who ran the loader gets the panic itself.
+var ErrLoaderPanicked = errors.New("cache loader panicked")
+
+// Loader fetches the value for a key on cache miss, e.g. from a database.
+type Loader[K comparable, V any] func(ctx context.Context, key K) (V, error)
+
+// LoadingCache is a read-through layer over a thread-safe Cache (SyncLFUCache, ShardedLFUCache):
+// it replaces the usual "Get, miss, load, Put" boilerplate with GetOrLoad.
+//
+// Concurrent misses on the same key share a single in-flight load (like golang.org/x/sync/singleflight),
+// so a hot key expiring does not send a hundred identical queries to the backend.
+// Only concurrent callers are merged: a miss that comes right after the load finished
+// (but before the value was visible in the cache) may start one more load, which is harmless.
+type LoadingCache[K comparable, V any] struct {
+	cache       Cache[K, V]
+	clock       Clock
+	negativeTTL time.Duration
+
+	mu       sync.Mutex
+	calls    map[K]*loadCall[V]
+	failures map[K]loadFailure
+	sweepAt  int
+}
+
+// failureSweepMin is the failures map size at which expired failures are swept for the first time.
+const failureSweepMin = 64
+
+type loadCall[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+	// leaderGone: the load failed because the ctx of the caller who ran it was done
+	leaderGone bool
+}
+
+type loadFailure struct {
+	err       error
+	expiresAt time.Time
+}
+
+// NewLoadingCache wraps c, which must be safe for concurrent use.
+// Understands WithNegativeTTL and WithClock options.
+// Time: O(1)
+// Space: O(1)
+func NewLoadingCache[K comparable, V any](c Cache[K, V], opts ...Option) *LoadingCache[K, V] {
+	o := newOptions(opts)
+	return &LoadingCache[K, V]{
+		cache:       c,
+		clock:       o.clock,
+		negativeTTL: o.negativeTTL,
+		calls:       make(map[K]*loadCall[V]),
+		failures:    make(map[K]loadFailure),
+		sweepAt:     failureSweepMin,
+	}
+}
+
+// GetOrLoad returns the cached value or loads it with loader and stores the result.
+//
+// The loader runs with the context of the caller who started the load. Other callers waiting
+// for the same key stop waiting when their own ctx is done, but the load itself goes on for the rest.
+// If the load fails only because the starter's ctx was done, waiters with a live ctx load again.
+// Loader errors are not stored in the cache; with WithNegativeTTL they are returned
+// to the following callers without calling the loader until the TTL passes.
+// Context cancellation and deadline errors are never remembered.
+// Time: O(1) + loader
+// Space: O(1) per in-flight key
+func (lc *LoadingCache[K, V]) GetOrLoad(ctx context.Context, key K, loader Loader[K, V]) (V, error) {
+	for {
+		if value, ok := lc.cache.Get(key); ok {
+			return value, nil
+		}
+
+		lc.mu.Lock()
+		if failure, ok := lc.failures[key]; ok {
+			if lc.clock().Before(failure.expiresAt) {
+				lc.mu.Unlock()
+				var zero V
+				return zero, failure.err
+			}
+			delete(lc.failures, key)
+		}
+
+		if call, ok := lc.calls[key]; ok {
+			lc.mu.Unlock()
+			select {
+			case <-call.done:
+				if call.leaderGone && ctx.Err() == nil {
+					// the starter gave up, not the backend: try again on our own ctx
+					continue
+				}
+				return call.value, call.err
+			case <-ctx.Done():
+				var zero V
+				return zero, ctx.Err()
+			}
+		}
+
+		call := &loadCall[V]{done: make(chan struct{})}
+		lc.calls[key] = call
+		lc.mu.Unlock()
+
+		lc.load(ctx, key, loader, call)
+		return call.value, call.err
+	}
+}
+
+// load runs the loader and publishes its result. Cleanup is deferred,
+// so even a panicking loader does not leave waiters hanging forever:
+// they get ErrLoaderPanicked, and the panic goes on in the caller who ran the loader.
+func (lc *LoadingCache[K, V]) load(ctx context.Context, key K, loader Loader[K, V], call *loadCall[V]) {
+	panicked := true
+	defer func() {
+		var r any
+		if panicked {
+			r = recover()
+			call.err = fmt.Errorf("%w: %v", ErrLoaderPanicked, r)
+		}
+		lc.mu.Lock()
+		delete(lc.calls, key)
+		lc.mu.Unlock()
+		close(call.done)
+		if panicked {
+			panic(r)
+		}
+	}()
+
+	call.value, call.err = loader(ctx, key)
+	panicked = false
+
+	if call.err != nil {
+		// A cancelled or timed out leader says nothing about the backend, the next caller may well succeed.
+		if isContextError(call.err) {
+			call.leaderGone = ctx.Err() != nil
+		} else if lc.negativeTTL > 0 {
+			lc.mu.Lock()
+			lc.rememberFailure(key, call.err)
+			lc.mu.Unlock()
+		}
+		return
+	}
+	lc.cache.Put(key, call.value)
+}
+
+// Time: O(1)
+// Space: O(1)
+func (lc *LoadingCache[K, V]) Get(key K) (V, bool) {
+	return lc.cache.Get(key)
+}
+
+// Put also forgets a remembered load failure for the key.
+// Time: O(1)
+// Space: O(1)
+func (lc *LoadingCache[K, V]) Put(key K, value V) {
+	lc.forgetFailure(key)
+	lc.cache.Put(key, value)
+}
+
+// Delete also forgets a remembered load failure for the key.
+// Time: O(1)
+// Space: O(1)
+func (lc *LoadingCache[K, V]) Delete(key K) bool {
+	lc.forgetFailure(key)
+	return lc.cache.Delete(key)
+}
+
+// Time: O(1), whatever the wrapped cache's Len costs
+// Space: O(1)
+func (lc *LoadingCache[K, V]) Len() int {
+	return lc.cache.Len()
+}
+
+// rememberFailure stores a failure for negativeTTL. Failures of keys nobody asks for again
+// would stay forever, so expired ones are swept whenever the map doubles since the last sweep.
+// Must be called with lc.mu held.
+// Time: O(1) amortized
+// Space: O(1)
+func (lc *LoadingCache[K, V]) rememberFailure(key K, err error) {
+	now := lc.clock()
+	if len(lc.failures) >= lc.sweepAt {
+		for k, failure := range lc.failures {
+			if !now.Before(failure.expiresAt) {
+				delete(lc.failures, k)
+			}
+		}
+		lc.sweepAt = max(2*len(lc.failures), failureSweepMin)
+	}
+	lc.failures[key] = loadFailure{err: err, expiresAt: now.Add(lc.negativeTTL)}
+}
+
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+func (lc *LoadingCache[K, V]) forgetFailure(key K) {
+	lc.mu.Lock()
+	delete(lc.failures, key)
+	lc.mu.Unlock()
+}
@@ -0,0 +1,311 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var errBackendDown = errors.New("backend is down")
+
+func TestLoadingCache_LoadsOnMissAndCaches(t *testing.T) {
+	lc := NewLoadingCache[string, int](NewSyncLFU[string, int](10))
+
+	calls := 0
+	loader := func(ctx context.Context, key string) (int, error) {
+		calls++
+		return len(key), nil
+	}
+
+	for range 3 {
+		val, err := lc.GetOrLoad(context.Background(), "hello", loader)
+		if err != nil || val != 5 {
+			t.Errorf("GetOrLoad = %d, %v, expected 5, nil", val, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("loader called %d times, expected 1", calls)
+	}
+}
+
+func TestLoadingCache_ConcurrentMissesShareOneLoad(t *testing.T) {
+	lc := NewLoadingCache[string, int](NewShardedLFU[string, int](4, 10))
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	loader := func(ctx context.Context, key string) (int, error) {
+		calls.Add(1)
+		<-release
+		return 42, nil
+	}
+
+	const callers = 50
+	var started, wg sync.WaitGroup
+	results := make([]int, callers)
+	errs := make([]error, callers)
+	for i := range callers {
+		started.Add(1)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			started.Done()
+			results[i], errs[i] = lc.GetOrLoad(context.Background(), "hot", loader)
+		}()
+	}
+
+	started.Wait()
+	// give callers a moment to pile up behind the first load
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Errorf("loader called %d times, expected 1", n)
+	}
+	for i := range callers {
+		if errs[i] != nil || results[i] != 42 {
+			t.Errorf("caller %d got %d, %v, expected 42, nil", i, results[i], errs[i])
+		}
+	}
+}
+
+func TestLoadingCache_ErrorsNotCachedByDefault(t *testing.T) {
+	lc := NewLoadingCache[string, int](NewSyncLFU[string, int](10))
+
+	calls := 0
+	loader := func(ctx context.Context, key string) (int, error) {
+		calls++
+		return 0, errBackendDown
+	}
+
+	for range 3 {
+		if _, err := lc.GetOrLoad(context.Background(), "k", loader); !errors.Is(err, errBackendDown) {
+			t.Errorf("GetOrLoad returned %v, expected errBackendDown", err)
+		}
+	}
+	if calls != 3 {
+		t.Errorf("loader called %d times, expected 3 without negative caching", calls)
+	}
+	if lc.Len() != 0 {
+		t.Errorf("Len is %d, failed loads must not be stored", lc.Len())
+	}
+}
+
+func TestLoadingCache_NegativeCaching(t *testing.T) {
+	clock := newFakeClock()
+	lc := NewLoadingCache[string, int](NewSyncLFU[string, int](10), WithNegativeTTL(time.Second), WithClock(clock.Now))
+
+	calls := 0
+	fail := true
+	loader := func(ctx context.Context, key string) (int, error) {
+		calls++
+		if fail {
+			return 0, errBackendDown
+		}
+		return 7, nil
+	}
+
+	lc.GetOrLoad(context.Background(), "k", loader)
+	if _, err := lc.GetOrLoad(context.Background(), "k", loader); !errors.Is(err, errBackendDown) {
+		t.Errorf("GetOrLoad returned %v, expected cached errBackendDown", err)
+	}
+	if calls != 1 {
+		t.Errorf("loader called %d times, expected 1 while failure is cached", calls)
+	}
+
+	fail = false
+	clock.Advance(time.Second)
+
+	val, err := lc.GetOrLoad(context.Background(), "k", loader)
+	if err != nil || val != 7 {
+		t.Errorf("GetOrLoad = %d, %v, expected 7, nil after negative TTL", val, err)
+	}
+	if calls != 2 {
+		t.Errorf("loader called %d times, expected 2", calls)
+	}
+}
+
+func TestLoadingCache_ContextErrorsNotNegativelyCached(t *testing.T) {
+	lc := NewLoadingCache[string, int](NewSyncLFU[string, int](10), WithNegativeTTL(time.Hour))
+	loader := func(ctx context.Context, key string) (int, error) {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		return 3, nil
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := lc.GetOrLoad(cancelled, "k", loader); !errors.Is(err, context.Canceled) {
+		t.Fatalf("GetOrLoad returned %v, expected context.Canceled", err)
+	}
+
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	if _, err := lc.GetOrLoad(expired, "k", loader); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("GetOrLoad returned %v, expected context.DeadlineExceeded", err)
+	}
+
+	val, err := lc.GetOrLoad(context.Background(), "k", loader)
+	if err != nil || val != 3 {
+		t.Errorf("GetOrLoad = %d, %v, expected 3, nil after the cancelled callers", val, err)
+	}
+}
+
+func TestLoadingCache_ExpiredFailuresAreSwept(t *testing.T) {
+	clock := newFakeClock()
+	lc := NewLoadingCache[int, int](NewSyncLFU[int, int](10), WithNegativeTTL(time.Second), WithClock(clock.Now))
+	loader := func(ctx context.Context, key int) (int, error) {
+		return 0, errBackendDown
+	}
+
+	// a stream of unknown ids, each asked for only once
+	for round := range 50 {
+		for i := range 100 {
+			lc.GetOrLoad(context.Background(), round*100+i, loader)
+		}
+		clock.Advance(time.Second)
+	}
+
+	if n := len(lc.failures); n > 2*100+failureSweepMin {
+		t.Errorf("%d failures remembered, expected expired ones to be swept", n)
+	}
+}
+
+func TestLoadingCache_CancelledLeaderDoesNotFailWaiters(t *testing.T) {
+	lc := NewLoadingCache[string, int](NewSyncLFU[string, int](10), WithNegativeTTL(time.Hour))
+
+	var calls atomic.Int32
+	started := make(chan struct{})
+	loader := func(ctx context.Context, key string) (int, error) {
+		if calls.Add(1) == 1 {
+			close(started)
+			<-ctx.Done()
+			return 0, ctx.Err()
+		}
+		return 42, nil
+	}
+
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	leaderDone := make(chan error)
+	go func() {
+		_, err := lc.GetOrLoad(leaderCtx, "k", loader)
+		leaderDone <- err
+	}()
+	<-started
+
+	waiterDone := make(chan error)
+	var val int
+	go func() {
+		var err error
+		val, err = lc.GetOrLoad(context.Background(), "k", loader)
+		waiterDone <- err
+	}()
+	// give the waiter a moment to join the in-flight load
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	if err := <-leaderDone; !errors.Is(err, context.Canceled) {
+		t.Errorf("leader got %v, expected context.Canceled", err)
+	}
+	if err := <-waiterDone; err != nil || val != 42 {
+		t.Errorf("waiter got %d, %v, expected 42, nil", val, err)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("loader called %d times, expected 2 (the waiter reloads)", n)
+	}
+}
+
+func TestLoadingCache_PutForgetsFailure(t *testing.T) {
+	lc := NewLoadingCache[string, int](NewSyncLFU[string, int](10), WithNegativeTTL(time.Hour))
+	lc.GetOrLoad(context.Background(), "k", func(ctx context.Context, key string) (int, error) {
+		return 0, errBackendDown
+	})
+
+	lc.Put("k", 1)
+	lc.Delete("k")
+
+	val, err := lc.GetOrLoad(context.Background(), "k", func(ctx context.Context, key string) (int, error) {
+		return 2, nil
+	})
+	if err != nil || val != 2 {
+		t.Errorf("GetOrLoad = %d, %v, expected 2, nil", val, err)
+	}
+}
+
+func TestLoadingCache_WaiterRespectsContext(t *testing.T) {
+	lc := NewLoadingCache[string, int](NewSyncLFU[string, int](10))
+
+	release := make(chan struct{})
+	loading := make(chan struct{})
+	loader := func(ctx context.Context, key string) (int, error) {
+		close(loading)
+		<-release
+		return 1, nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		lc.GetOrLoad(context.Background(), "slow", loader)
+	}()
+	<-loading
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := lc.GetOrLoad(ctx, "slow", loader); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("GetOrLoad returned %v, expected context.DeadlineExceeded", err)
+	}
+
+	close(release)
+	<-done
+	if val, ok := lc.Get("slow"); !ok || val != 1 {
+		t.Errorf("Get(slow) = %d, %v, load should complete for the first caller", val, ok)
+	}
+}
+
+func TestLoadingCache_PanickingLoaderReleasesWaiters(t *testing.T) {
+	lc := NewLoadingCache[string, int](NewSyncLFU[string, int](10))
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	leaderDone := make(chan struct{})
+	go func() {
+		defer close(leaderDone)
+		defer func() {
+			if recover() == nil {
+				t.Error("panic should propagate to the caller who ran the loader")
+			}
+		}()
+		lc.GetOrLoad(context.Background(), "k", func(ctx context.Context, key string) (int, error) {
+			close(started)
+			<-release
+			panic("boom")
+		})
+	}()
+
+	<-started
+	waiterDone := make(chan error)
+	go func() {
+		_, err := lc.GetOrLoad(context.Background(), "k", func(ctx context.Context, key string) (int, error) {
+			t.Error("waiter should share the in-flight load, not start its own")
+			return 1, nil
+		})
+		waiterDone <- err
+	}()
+
+	// give the waiter a moment to join the in-flight load
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	<-leaderDone
+
+	if err := <-waiterDone; !errors.Is(err, ErrLoaderPanicked) {
+		t.Errorf("waiter got %v, expected ErrLoaderPanicked", err)
+	}
+	if len(lc.calls) != 0 {
+		t.Error("in-flight call should be cleaned up after loader panic")
+	}
+}
@@ -0,0 +1,41 @@
+package cache
+
+// LRUPolicy evicts the least recently used key. The classic baseline every other policy is compared to.
+type LRUPolicy[K comparable] struct {
+	capacity int
+	keys     *keyList[K]
+}
+
+// Time: O(1)
+// Space: O(capacity) when full
+func NewLRUPolicy[K comparable](capacity int) *LRUPolicy[K] {
+	return &LRUPolicy[K]{capacity: capacity, keys: newKeyList[K]()}
+}
+
+// Time: O(1)
+// Space: O(1)
+func (p *LRUPolicy[K]) Hit(key K) {
+	p.keys.MoveToFront(key)
+}
+
+// Time: O(1)
+// Space: O(1)
+func (p *LRUPolicy[K]) Insert(key K) (K, bool) {
+	if p.capacity <= 0 {
+		return key, true
+	}
+
+	var victim K
+	evicted := false
+	if p.keys.Len() >= p.capacity {
+		victim, evicted = p.keys.PopBack()
+	}
+	p.keys.PushFront(key)
+	return victim, evicted
+}
+
+// Time: O(1)
+// Space: O(1)
+func (p *LRUPolicy[K]) Remove(key K) {
+	p.keys.Remove(key)
+}
@@ -0,0 +1,21 @@
+package cache
+
+import "testing"
+
+func TestLRUPolicy_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewPolicyCache[string, int](NewLRUPolicy[string](3))
+	c.Put("a", 1)
+	c.Put("b", 2)
+	c.Put("c", 3)
+	c.Get("a")
+	c.Put("d", 4)
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("b should be evicted (least recently used)")
+	}
+	for _, key := range []string{"a", "c", "d"} {
+		if _, ok := c.Get(key); !ok {
+			t.Errorf("%s should exist", key)
+		}
+	}
+}
@@ -1,8 +1,18 @@
 package cache
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
-var ErrKeyNotFound = errors.New("key not found in cache")
+var (
+	ErrKeyNotFound    = errors.New("key not found in cache")
+	ErrInvalidSize    = errors.New("cache size and probing step must be positive")
+	ErrStepNotCoprime = errors.New("probing step must be coprime with cache size")
+)
+
+// When live entries plus tombstones take more than this share of slots, the table is rehashed.
+const REHASH_THRESHOLD = 0.7
 
 // NativeCache is a simple LFU cache based on hash table with open addressing.
 // This is the "naive" implementation as suggested by the lab task.
@@ -11,26 +21,78 @@ var ErrKeyNotFound = errors.New("key not found in cache")
 // This requires O(n) scan of hits[] array - inefficient compared to LFUCache,
 // but simpler to understand and matches the original task requirements.
 //
+// Deletion uses tombstones (see the broken probe chain story in lfu_cache.go):
+// a deleted slot is marked slotDeleted, findKey walks over it, and Put may reuse it.
+// Tombstones pile up, so once live entries + tombstones cross REHASH_THRESHOLD of the table,
+// everything is rehashed into a clean table - bigger one if live entries alone need it.
+// The initial size stays the cache capacity: the table grows to keep probe chains short,
+// but the cache never holds more than `capacity` entries, the min-hits eviction still applies.
+//
 // See LFUCache in lfu_cache.go for O(1) eviction implementation.
 
+type slotState uint8
+
+const (
+	slotEmpty slotState = iota
+	slotUsed
+	slotDeleted
+)
+
 type NativeCache[T any] struct {
-	size   int
-	step   int
-	slots  []string
-	values []T
-	hits   []int
+	capacity   int
+	size       int
+	step       int
+	count      int
+	tombstones int
+	states     []slotState
+	slots      []string
+	values     []T
+	hits       []int
+	stats      Stats
+	onEvict    func(key string, value T, reason EvictReason)
 }
 
+// NewNativeCache validates parameters: with step sharing a divisor with size,
+// probing visits only part of the table and may never find a free slot.
+// Time: O(n) for slices allocation
+// Space: O(n) where n = size
+func NewNativeCache[T any](size int, step int) (*NativeCache[T], error) {
+	if size <= 0 || step <= 0 {
+		return nil, ErrInvalidSize
+	}
+	if gcd(size, step) != 1 {
+		return nil, fmt.Errorf("%w: size %d, step %d", ErrStepNotCoprime, size, step)
+	}
+
+	return &NativeCache[T]{
+		capacity: size,
+		size:     size,
+		step:     step,
+		states:   make([]slotState, size),
+		slots:    make([]string, size),
+		values:   make([]T, size),
+		hits:     make([]int, size),
+	}, nil
+}
+
+// InitNativeCache is the lab-task constructor, it panics on parameters NewNativeCache rejects.
 // Time: O(n) for slices allocation
 // Space: O(n) where n = size
 func InitNativeCache[T any](size int, step int) NativeCache[T] {
-	return NativeCache[T]{
-		size:   size,
-		step:   step,
-		slots:  make([]string, size),
-		values: make([]T, size),
-		hits:   make([]int, size),
+	nc, err := NewNativeCache[T](size, step)
+	if err != nil {
+		panic(err)
+	}
+	return *nc
+}
+
+// Time: O(log(min(a, b)))
+// Space: O(1)
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
 	}
+	return a
 }
 
 // Time: O(k) where k = len(key)
@@ -43,13 +105,14 @@ func (nc *NativeCache[T]) HashFun(key string) int {
 	return sum % nc.size
 }
 
+// seekSlot finds a slot for a key that is not stored yet: the first tombstone or empty slot in its probe chain.
 // Time: O(n) worst case (full table with collisions)
 // Space: O(1)
 func (nc *NativeCache[T]) seekSlot(key string) int {
 	idx := nc.HashFun(key)
 
 	for i := 0; i < nc.size; i++ {
-		if nc.slots[idx] == "" || nc.slots[idx] == key {
+		if nc.states[idx] != slotUsed {
 			return idx
 		}
 		idx = (idx + nc.step) % nc.size
@@ -61,12 +124,10 @@ func (nc *NativeCache[T]) seekSlot(key string) int {
 // Time: O(n) - linear scan of hits array
 // Space: O(1)
 func (nc *NativeCache[T]) findMinHitsIdx() int {
-	minIdx := 0
-	minHits := nc.hits[0]
+	minIdx := -1
 
 	for i, h := range nc.hits {
-		if h < minHits {
-			minHits = h
+		if nc.states[i] == slotUsed && (minIdx == -1 || h < nc.hits[minIdx]) {
 			minIdx = i
 		}
 	}
@@ -74,18 +135,122 @@ func (nc *NativeCache[T]) findMinHitsIdx() int {
 	return minIdx
 }
 
-// Time: O(n) worst case
-// Space: O(1)
+// Time: O(n) worst case, O(n) rehash from time to time
+// Space: O(1), O(n) during rehash
 func (nc *NativeCache[T]) Put(key string, value T) {
-	idx := nc.seekSlot(key)
+	if idx := nc.findKey(key); idx != -1 {
+		nc.notifyEvict(idx, EvictReplaced)
+		nc.values[idx] = value
+		return
+	}
 
-	if idx == -1 {
-		idx = nc.findMinHitsIdx()
-		nc.hits[idx] = 0
+	if nc.count >= nc.capacity {
+		idx := nc.findMinHitsIdx()
+		nc.stats.Evictions++
+		nc.notifyEvict(idx, EvictCapacity)
+		nc.removeAt(idx)
+	}
+
+	if float64(nc.count+nc.tombstones+1) > REHASH_THRESHOLD*float64(nc.size) {
+		nc.rehash(nc.count + 1)
 	}
 
+	idx := nc.seekSlot(key)
+	if nc.states[idx] == slotDeleted {
+		nc.tombstones--
+	}
+	nc.states[idx] = slotUsed
 	nc.slots[idx] = key
 	nc.values[idx] = value
+	nc.hits[idx] = 0
+	nc.count++
+}
+
+// Delete removes the key and leaves a tombstone in its slot, so probe chains going through it stay intact.
+// Time: O(n) worst case (probing through collisions)
+// Space: O(1)
+func (nc *NativeCache[T]) Delete(key string) bool {
+	idx := nc.findKey(key)
+	if idx == -1 {
+		return false
+	}
+
+	nc.notifyEvict(idx, EvictDeleted)
+	nc.removeAt(idx)
+	return true
+}
+
+// Time: O(1)
+// Space: O(1)
+func (nc *NativeCache[T]) removeAt(idx int) {
+	var zero T
+	nc.states[idx] = slotDeleted
+	nc.slots[idx] = ""
+	nc.values[idx] = zero
+	nc.hits[idx] = 0
+	nc.count--
+	nc.tombstones++
+}
+
+// rehash moves live entries (with their hits) into a fresh table without tombstones.
+// The table only grows when `needed` live entries would not fit under REHASH_THRESHOLD,
+// and the new size is bumped until it is coprime with step again.
+// Time: O(n)
+// Space: O(n)
+func (nc *NativeCache[T]) rehash(needed int) {
+	newSize := nc.size
+	for float64(needed) > REHASH_THRESHOLD*float64(newSize) {
+		newSize *= 2
+	}
+	for gcd(newSize, nc.step) != 1 {
+		newSize++
+	}
+
+	oldStates, oldSlots, oldValues, oldHits := nc.states, nc.slots, nc.values, nc.hits
+	nc.size = newSize
+	nc.states = make([]slotState, newSize)
+	nc.slots = make([]string, newSize)
+	nc.values = make([]T, newSize)
+	nc.hits = make([]int, newSize)
+	nc.tombstones = 0
+
+	for i, state := range oldStates {
+		if state != slotUsed {
+			continue
+		}
+		idx := nc.seekSlot(oldSlots[i])
+		nc.states[idx] = slotUsed
+		nc.slots[idx] = oldSlots[i]
+		nc.values[idx] = oldValues[i]
+		nc.hits[idx] = oldHits[i]
+	}
+}
+
+// OnEvict registers a callback for evicted and replaced entries, same contract as LFUCache.OnEvict.
+// Time: O(1)
+// Space: O(1)
+func (nc *NativeCache[T]) OnEvict(fn func(key string, value T, reason EvictReason)) {
+	nc.onEvict = fn
+}
+
+// Time: O(1) + callback
+// Space: O(1)
+func (nc *NativeCache[T]) notifyEvict(idx int, reason EvictReason) {
+	if nc.onEvict != nil {
+		nc.onEvict(nc.slots[idx], nc.values[idx], reason)
+	}
+}
+
+// Time: O(1)
+// Space: O(1)
+func (nc *NativeCache[T]) Stats() Stats {
+	return nc.stats
+}
+
+// Time: O(1)
+// Space: O(1)
+func (nc *NativeCache[T]) Len() int {
+	return nc.count
 }
 
 // Time: O(n) worst case (probing through collisions)
@@ -94,10 +259,12 @@ func (nc *NativeCache[T]) Get(key string) (T, error) {
 	idx := nc.findKey(key)
 
 	if idx == -1 {
+		nc.stats.Misses++
 		var zero T
 		return zero, ErrKeyNotFound
 	}
 
+	nc.stats.Hits++
 	nc.hits[idx]++
 	return nc.values[idx], nil
 }
@@ -108,17 +275,20 @@ func (nc *NativeCache[T]) IsKey(key string) bool {
 	return nc.findKey(key) != -1
 }
 
+// findKey stops only at a really empty slot: tombstones mean "something was here, keep probing".
 // Time: O(n) worst case
 // Space: O(1)
 func (nc *NativeCache[T]) findKey(key string) int {
 	idx := nc.HashFun(key)
 
 	for i := 0; i < nc.size; i++ {
-		if nc.slots[idx] == key {
-			return idx
-		}
-		if nc.slots[idx] == "" {
+		switch nc.states[idx] {
+		case slotEmpty:
 			return -1
+		case slotUsed:
+			if nc.slots[idx] == key {
+				return idx
+			}
 		}
 		idx = (idx + nc.step) % nc.size
 	}
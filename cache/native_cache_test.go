@@ -1,6 +1,8 @@
-package lfucache
+package cache
 
 import (
+	"errors"
+	"fmt"
 	"testing"
 )
 
@@ -397,3 +399,238 @@ func TestNativeCache_GenericTypes(t *testing.T) {
 		t.Errorf("Got %+v, expected Alice/30", alice)
 	}
 }
+
+func TestNativeCache_OnEvict_Capacity(t *testing.T) {
+	nc := InitNativeCache[int](2, 1)
+
+	var evictedKey string
+	var evictedValue int
+	var evictedReason EvictReason
+	calls := 0
+	nc.OnEvict(func(key string, value int, reason EvictReason) {
+		evictedKey, evictedValue, evictedReason = key, value, reason
+		calls++
+	})
+
+	nc.Put("old", 1)
+	nc.Get("old")
+	nc.Put("cold", 2)
+	nc.Put("new", 3)
+
+	if calls != 1 {
+		t.Fatalf("OnEvict called %d times, expected 1", calls)
+	}
+	if evictedKey != "cold" || evictedValue != 2 || evictedReason != EvictCapacity {
+		t.Errorf("evicted (%q, %d, %v), expected (cold, 2, capacity)", evictedKey, evictedValue, evictedReason)
+	}
+	if nc.Stats().Evictions != 1 {
+		t.Errorf("Evictions is %d, expected 1", nc.Stats().Evictions)
+	}
+}
+
+func TestNativeCache_OnEvict_Replaced(t *testing.T) {
+	nc := InitNativeCache[int](17, 3)
+
+	var reasons []EvictReason
+	nc.OnEvict(func(key string, value int, reason EvictReason) {
+		reasons = append(reasons, reason)
+	})
+
+	nc.Put("key", 1)
+	nc.Put("key", 2)
+
+	if len(reasons) != 1 || reasons[0] != EvictReplaced {
+		t.Errorf("reasons = %v, expected [replaced]", reasons)
+	}
+}
+
+func TestNativeCache_Stats(t *testing.T) {
+	nc := InitNativeCache[int](17, 3)
+	nc.Put("a", 1)
+	nc.Get("a")
+	nc.Get("a")
+	nc.Get("missing")
+
+	stats := nc.Stats()
+	if stats.Hits != 2 || stats.Misses != 1 {
+		t.Errorf("stats = %+v, expected 2 hits and 1 miss", stats)
+	}
+	if ratio := stats.HitRatio(); ratio < 0.66 || ratio > 0.67 {
+		t.Errorf("HitRatio is %v, expected ~0.667", ratio)
+	}
+}
+
+func TestNewNativeCache_StepMustBeCoprime(t *testing.T) {
+	if _, err := NewNativeCache[int](10, 4); !errors.Is(err, ErrStepNotCoprime) {
+		t.Errorf("NewNativeCache(10, 4) returned %v, expected ErrStepNotCoprime", err)
+	}
+	if _, err := NewNativeCache[int](0, 1); !errors.Is(err, ErrInvalidSize) {
+		t.Errorf("NewNativeCache(0, 1) returned %v, expected ErrInvalidSize", err)
+	}
+	if _, err := NewNativeCache[int](10, 3); err != nil {
+		t.Errorf("NewNativeCache(10, 3) returned %v, expected nil", err)
+	}
+}
+
+func TestInitNativeCache_PanicsOnBadStep(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("InitNativeCache(6, 3) should panic, step 3 visits only 2 of 6 slots")
+		}
+	}()
+	InitNativeCache[int](6, 3)
+}
+
+func TestNativeCache_Delete(t *testing.T) {
+	nc := InitNativeCache[int](17, 3)
+	nc.Put("a", 1)
+
+	if !nc.Delete("a") {
+		t.Error("Delete should return true for existing key")
+	}
+	if nc.Delete("a") {
+		t.Error("Delete should return false for missing key")
+	}
+	if nc.IsKey("a") {
+		t.Error("a should not exist after Delete")
+	}
+	if nc.Len() != 0 {
+		t.Errorf("Len is %d, expected 0", nc.Len())
+	}
+}
+
+func TestNativeCache_DeleteInMiddleOfCollisionChain(t *testing.T) {
+	nc := InitNativeCache[int](5, 1)
+
+	// "a", "f" and "k" all hash to slot 2: a -> 2, f -> 3, k -> 4
+	nc.Put("a", 1)
+	nc.Put("f", 2)
+	nc.Put("k", 3)
+	idxF := nc.findKey("f")
+
+	nc.Delete("f")
+
+	if nc.states[idxF] != slotDeleted {
+		t.Errorf("slot %d should hold a tombstone", idxF)
+	}
+	val, err := nc.Get("k")
+	if err != nil || val != 3 {
+		t.Errorf("Get(k) = %d, %v: the probe chain must go through the tombstone", val, err)
+	}
+	if !nc.IsKey("a") {
+		t.Error("a should still exist")
+	}
+}
+
+func TestNativeCache_PutReusesTombstone(t *testing.T) {
+	nc := InitNativeCache[int](17, 1)
+
+	// "a" (97), "r" (114) and "0S" (48+83) all hash to slot 12
+	nc.Put("a", 1)
+	nc.Put("r", 2)
+	nc.Put("0S", 3)
+	idxR := nc.findKey("r")
+
+	nc.Delete("r")
+	nc.Put("0d", 4) // 48+100, also slot 12: first free slot in the chain is the tombstone
+
+	if nc.findKey("0d") != idxR {
+		t.Errorf("0d is at slot %d, expected reused tombstone slot %d", nc.findKey("0d"), idxR)
+	}
+	if nc.tombstones != 0 {
+		t.Errorf("tombstones is %d, expected 0", nc.tombstones)
+	}
+	if !nc.IsKey("0S") {
+		t.Error("0S should still be reachable")
+	}
+}
+
+func TestNativeCache_DeleteThenPutSameKeyDoesNotDuplicate(t *testing.T) {
+	nc := InitNativeCache[int](5, 1)
+	nc.Put("a", 1)
+	nc.Put("f", 2)
+	nc.Delete("a")
+	nc.Put("f", 20)
+
+	if nc.Len() != 1 {
+		t.Errorf("Len is %d, expected 1 (f must be updated, not inserted again)", nc.Len())
+	}
+	if val, _ := nc.Get("f"); val != 20 {
+		t.Errorf("Get(f) = %d, expected 20", val)
+	}
+}
+
+func TestNativeCache_RehashCleansTombstones(t *testing.T) {
+	nc := InitNativeCache[int](17, 3)
+	for i := range 100 {
+		key := fmt.Sprint("k", i)
+		nc.Put(key, i)
+		nc.Delete(key)
+	}
+
+	if float64(nc.count+nc.tombstones) > REHASH_THRESHOLD*float64(nc.size) {
+		t.Errorf("count %d + tombstones %d exceed threshold of size %d", nc.count, nc.tombstones, nc.size)
+	}
+	if nc.size != 17 {
+		t.Errorf("size is %d, expected 17: only tombstones, no need to grow", nc.size)
+	}
+
+	nc.Put("alive", 1)
+	if !nc.IsKey("alive") {
+		t.Error("alive should exist")
+	}
+}
+
+func TestNativeCache_GrowsButKeepsCapacity(t *testing.T) {
+	nc := InitNativeCache[int](10, 3)
+	for i := range 10 {
+		nc.Put(fmt.Sprint("k", i), i)
+	}
+
+	if nc.size <= 10 {
+		t.Errorf("size is %d, table should grow to keep load under %v", nc.size, REHASH_THRESHOLD)
+	}
+	if gcd(nc.size, nc.step) != 1 {
+		t.Errorf("size %d is not coprime with step %d after growth", nc.size, nc.step)
+	}
+	for i := range 10 {
+		val, err := nc.Get(fmt.Sprint("k", i))
+		if err != nil || val != i {
+			t.Errorf("Get(k%d) = %d, %v after rehash", i, val, err)
+		}
+	}
+
+	nc.Put("extra", 100)
+	if nc.Len() != 10 {
+		t.Errorf("Len is %d, expected capacity 10", nc.Len())
+	}
+}
+
+func TestNativeCache_RehashKeepsHits(t *testing.T) {
+	nc := InitNativeCache[int](3, 1)
+	nc.Put("a", 1)
+	nc.Get("a")
+	nc.Get("a")
+	nc.Put("b", 2)
+	nc.Put("c", 3)
+
+	if hits := nc.GetHits("a"); hits != 2 {
+		t.Errorf("a.hits is %d, expected 2 after rehash", hits)
+	}
+}
+
+func TestNativeCache_DeleteFiresCallback(t *testing.T) {
+	nc := InitNativeCache[int](17, 3)
+
+	var reasons []EvictReason
+	nc.OnEvict(func(key string, value int, reason EvictReason) {
+		reasons = append(reasons, reason)
+	})
+
+	nc.Put("", 1)
+	nc.Delete("")
+
+	if len(reasons) != 1 || reasons[0] != EvictDeleted {
+		t.Errorf("reasons = %v, expected [deleted] (empty key is a normal key, not an empty slot)", reasons)
+	}
+}
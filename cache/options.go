@@ -0,0 +1,58 @@
+package cache
+
+import "time"
+
+// Clock returns the current time. LFUCache asks it instead of time.Now directly,
+// so tests can move time forward by hand and check expiry deterministically.
+type Clock func() time.Time
+
+type options struct {
+	defaultTTL    time.Duration
+	clock         Clock
+	aging         AgingMode
+	halvingPeriod int
+	maxCost       int64
+	negativeTTL   time.Duration
+	flushInterval time.Duration
+}
+
+// Option configures a cache at construction time, e.g. NewLFU[string, int](100, WithDefaultTTL(time.Minute)).
+type Option func(*options)
+
+// WithDefaultTTL makes every Put expire after ttl. Zero (the default) means entries never expire.
+func WithDefaultTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.defaultTTL = ttl
+	}
+}
+
+// WithClock replaces time.Now as the source of time for expiry checks.
+func WithClock(clock Clock) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
+
+// WithMaxCost limits the summary cost of entries stored with PutWithCost (0, the default, means no limit).
+// The entry-count capacity still applies on top of it.
+func WithMaxCost(budget int64) Option {
+	return func(o *options) {
+		o.maxCost = budget
+	}
+}
+
+// WithNegativeTTL makes LoadingCache remember loader errors for ttl,
+// so a failing backend is not hammered by every following miss. Zero (the default) disables it.
+func WithNegativeTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.negativeTTL = ttl
+	}
+}
+
+func newOptions(opts []Option) options {
+	o := options{clock: time.Now}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
@@ -0,0 +1,216 @@
+package cache
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+)
+
+var ErrUnknownPolicy = errors.New("unknown eviction policy")
+
+const (
+	PolicyLRU   = "lru"
+	PolicyLFU   = "lfu"
+	PolicyARC   = "arc"
+	Policy2Q    = "2q"
+	PolicySIEVE = "sieve"
+)
+
+// Policy decides only *which key* leaves the cache, it never sees values.
+// Values live in PolicyCache, so every policy is a small bookkeeping structure over keys,
+// and all of them can be swapped behind the same Cache interface.
+//
+// Insert may return the inserted key itself as a victim - that is how zero capacity is expressed.
+type Policy[K comparable] interface {
+	// Hit is called when a stored key is read or overwritten.
+	Hit(key K)
+	// Insert is called for a key which is not stored yet.
+	// If the policy has to make room, it returns the key which must be dropped.
+	Insert(key K) (victim K, evicted bool)
+	// Remove is called when a stored key is deleted by the caller.
+	Remove(key K)
+}
+
+// NewPolicy builds a policy by name (PolicyLRU, PolicyLFU, PolicyARC, Policy2Q, PolicySIEVE),
+// which is handy when the policy comes from configuration.
+// Time: O(1)
+// Space: O(1) initialization
+func NewPolicy[K comparable](name string, capacity int) (Policy[K], error) {
+	switch name {
+	case PolicyLRU:
+		return NewLRUPolicy[K](capacity), nil
+	case PolicyLFU:
+		return NewLFUPolicy[K](capacity), nil
+	case PolicyARC:
+		return NewARCPolicy[K](capacity), nil
+	case Policy2Q:
+		return New2QPolicy[K](capacity), nil
+	case PolicySIEVE:
+		return NewSIEVEPolicy[K](capacity), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownPolicy, name)
+	}
+}
+
+// PolicyCache stores values in a plain map and delegates eviction decisions to a Policy.
+type PolicyCache[K comparable, V any] struct {
+	policy  Policy[K]
+	values  map[K]V
+	stats   Stats
+	onEvict func(key K, value V, reason EvictReason)
+}
+
+// Time: O(1)
+// Space: O(1) initialization
+func NewPolicyCache[K comparable, V any](policy Policy[K]) *PolicyCache[K, V] {
+	return &PolicyCache[K, V]{
+		policy: policy,
+		values: make(map[K]V),
+	}
+}
+
+// NewCache is a shortcut for NewPolicyCache(NewPolicy(name, capacity)).
+// Time: O(1)
+// Space: O(1) initialization
+func NewCache[K comparable, V any](policy string, capacity int) (*PolicyCache[K, V], error) {
+	p, err := NewPolicy[K](policy, capacity)
+	if err != nil {
+		return nil, err
+	}
+	return NewPolicyCache[K, V](p), nil
+}
+
+// Time: O(1), all policies here keep O(1) bookkeeping
+// Space: O(1)
+func (c *PolicyCache[K, V]) Get(key K) (V, bool) {
+	value, ok := c.values[key]
+	if !ok {
+		c.stats.Misses++
+		return value, false
+	}
+
+	c.stats.Hits++
+	c.policy.Hit(key)
+	return value, true
+}
+
+// Time: O(1) amortized (SIEVE hand may walk, see sieve_policy.go)
+// Space: O(1)
+func (c *PolicyCache[K, V]) Put(key K, value V) {
+	if old, ok := c.values[key]; ok {
+		c.values[key] = value
+		c.notifyEvict(key, old, EvictReplaced)
+		c.policy.Hit(key)
+		return
+	}
+
+	c.values[key] = value
+	victim, evicted := c.policy.Insert(key)
+	if !evicted {
+		return
+	}
+
+	old := c.values[victim]
+	delete(c.values, victim)
+	c.stats.Evictions++
+	c.notifyEvict(victim, old, EvictCapacity)
+}
+
+// Time: O(1)
+// Space: O(1)
+func (c *PolicyCache[K, V]) Delete(key K) bool {
+	value, ok := c.values[key]
+	if !ok {
+		return false
+	}
+
+	delete(c.values, key)
+	c.policy.Remove(key)
+	c.notifyEvict(key, value, EvictDeleted)
+	return true
+}
+
+// Time: O(1)
+// Space: O(1)
+func (c *PolicyCache[K, V]) Len() int {
+	return len(c.values)
+}
+
+// Time: O(1)
+// Space: O(1)
+func (c *PolicyCache[K, V]) Stats() Stats {
+	return c.stats
+}
+
+// OnEvict has the same contract as LFUCache.OnEvict.
+// Time: O(1)
+// Space: O(1)
+func (c *PolicyCache[K, V]) OnEvict(fn func(key K, value V, reason EvictReason)) {
+	c.onEvict = fn
+}
+
+func (c *PolicyCache[K, V]) notifyEvict(key K, value V, reason EvictReason) {
+	if c.onEvict != nil {
+		c.onEvict(key, value, reason)
+	}
+}
+
+// keyList is an LRU-ordered list of keys with O(1) membership check:
+// front is the most recently used key, back is the least recently used one.
+// LRU, 2Q and ARC are all built from a few of these.
+type keyList[K comparable] struct {
+	order *list.List
+	items map[K]*list.Element
+}
+
+func newKeyList[K comparable]() *keyList[K] {
+	return &keyList[K]{order: list.New(), items: make(map[K]*list.Element)}
+}
+
+// Time: O(1)
+func (l *keyList[K]) Len() int {
+	return l.order.Len()
+}
+
+// Time: O(1)
+func (l *keyList[K]) Contains(key K) bool {
+	_, ok := l.items[key]
+	return ok
+}
+
+// Time: O(1)
+func (l *keyList[K]) PushFront(key K) {
+	l.items[key] = l.order.PushFront(key)
+}
+
+// Time: O(1)
+func (l *keyList[K]) MoveToFront(key K) {
+	if elem, ok := l.items[key]; ok {
+		l.order.MoveToFront(elem)
+	}
+}
+
+// Time: O(1)
+func (l *keyList[K]) Remove(key K) bool {
+	elem, ok := l.items[key]
+	if !ok {
+		return false
+	}
+	l.order.Remove(elem)
+	delete(l.items, key)
+	return true
+}
+
+// PopBack removes and returns the least recently used key.
+// Time: O(1)
+func (l *keyList[K]) PopBack() (K, bool) {
+	elem := l.order.Back()
+	if elem == nil {
+		var zero K
+		return zero, false
+	}
+	key := elem.Value.(K)
+	l.order.Remove(elem)
+	delete(l.items, key)
+	return key, true
+}
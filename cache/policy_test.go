@@ -0,0 +1,201 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// cacheFactories lists every Cache implementation that must pass the shared conformance suite.
+func cacheFactories() map[string]func(capacity int) Cache[string, int] {
+	factories := map[string]func(capacity int) Cache[string, int]{
+		"LFUCache": func(capacity int) Cache[string, int] {
+			return NewLFU[string, int](capacity)
+		},
+		"SyncLFUCache": func(capacity int) Cache[string, int] {
+			return NewSyncLFU[string, int](capacity)
+		},
+		"ShardedLFUCache": func(capacity int) Cache[string, int] {
+			return NewShardedLFU[string, int](1, capacity)
+		},
+		"TinyLFUCache": func(capacity int) Cache[string, int] {
+			return NewTinyLFU[string, int](capacity)
+		},
+	}
+
+	for _, name := range []string{PolicyLRU, PolicyLFU, PolicyARC, Policy2Q, PolicySIEVE} {
+		factories["policy/"+name] = func(capacity int) Cache[string, int] {
+			c, err := NewCache[string, int](name, capacity)
+			if err != nil {
+				panic(err)
+			}
+			return c
+		}
+	}
+	return factories
+}
+
+func TestCacheConformance(t *testing.T) {
+	for name, newCache := range cacheFactories() {
+		t.Run(name, func(t *testing.T) {
+			t.Run("PutGet", func(t *testing.T) {
+				c := newCache(10)
+				c.Put("a", 1)
+				c.Put("b", 2)
+
+				if val, ok := c.Get("a"); !ok || val != 1 {
+					t.Errorf("Get(a) = %d, %v, expected 1, true", val, ok)
+				}
+				if val, ok := c.Get("b"); !ok || val != 2 {
+					t.Errorf("Get(b) = %d, %v, expected 2, true", val, ok)
+				}
+			})
+
+			t.Run("Miss", func(t *testing.T) {
+				c := newCache(10)
+				c.Put("a", 1)
+
+				if val, ok := c.Get("missing"); ok || val != 0 {
+					t.Errorf("Get(missing) = %d, %v, expected 0, false", val, ok)
+				}
+			})
+
+			t.Run("UpdateExistingKey", func(t *testing.T) {
+				c := newCache(10)
+				c.Put("a", 1)
+				c.Put("a", 2)
+
+				if val, _ := c.Get("a"); val != 2 {
+					t.Errorf("Get(a) = %d, expected 2", val)
+				}
+				if c.Len() != 1 {
+					t.Errorf("Len is %d, expected 1", c.Len())
+				}
+			})
+
+			t.Run("Delete", func(t *testing.T) {
+				c := newCache(10)
+				c.Put("a", 1)
+				c.Put("b", 2)
+
+				if !c.Delete("a") {
+					t.Error("Delete(a) should return true")
+				}
+				if c.Delete("a") {
+					t.Error("second Delete(a) should return false")
+				}
+				if _, ok := c.Get("a"); ok {
+					t.Error("a should not exist after Delete")
+				}
+				if c.Len() != 1 {
+					t.Errorf("Len is %d, expected 1", c.Len())
+				}
+			})
+
+			t.Run("ZeroCapacity", func(t *testing.T) {
+				c := newCache(0)
+				c.Put("a", 1)
+
+				if _, ok := c.Get("a"); ok {
+					t.Error("zero capacity cache should not store anything")
+				}
+				if c.Len() != 0 {
+					t.Errorf("Len is %d, expected 0", c.Len())
+				}
+			})
+
+			t.Run("NeverExceedsCapacity", func(t *testing.T) {
+				c := newCache(16)
+				for i := range 1000 {
+					key := fmt.Sprint(i % 97)
+					c.Put(key, i)
+					c.Get(fmt.Sprint(i % 13))
+					if i%7 == 0 {
+						c.Delete(fmt.Sprint(i % 31))
+					}
+					if c.Len() > 16 {
+						t.Fatalf("Len is %d after %d operations, expected at most 16", c.Len(), i+1)
+					}
+				}
+			})
+
+			t.Run("StoredValuesAreConsistent", func(t *testing.T) {
+				c := newCache(8)
+				for i := range 100 {
+					c.Put(fmt.Sprint(i%20), i%20)
+				}
+				for i := range 20 {
+					if val, ok := c.Get(fmt.Sprint(i)); ok && val != i {
+						t.Errorf("Get(%d) = %d, value does not belong to the key", i, val)
+					}
+				}
+			})
+
+			t.Run("FillsUpToCapacity", func(t *testing.T) {
+				c := newCache(5)
+				for i := range 5 {
+					c.Put(fmt.Sprint(i), i)
+				}
+				if c.Len() != 5 {
+					t.Errorf("Len is %d, expected 5", c.Len())
+				}
+			})
+		})
+	}
+}
+
+func TestNewPolicy_Unknown(t *testing.T) {
+	_, err := NewPolicy[string]("fifo-ish", 10)
+	if !errors.Is(err, ErrUnknownPolicy) {
+		t.Errorf("NewPolicy returned %v, expected ErrUnknownPolicy", err)
+	}
+
+	if _, err := NewCache[string, int]("fifo-ish", 10); !errors.Is(err, ErrUnknownPolicy) {
+		t.Errorf("NewCache returned %v, expected ErrUnknownPolicy", err)
+	}
+}
+
+func TestPolicyCache_OnEvictAndStats(t *testing.T) {
+	c, _ := NewCache[string, int](PolicyLRU, 1)
+
+	var reasons []EvictReason
+	c.OnEvict(func(key string, value int, reason EvictReason) {
+		reasons = append(reasons, reason)
+	})
+
+	c.Put("a", 1)
+	c.Put("a", 2)
+	c.Put("b", 3)
+	c.Delete("b")
+	c.Get("a")
+
+	expected := []EvictReason{EvictReplaced, EvictCapacity, EvictDeleted}
+	if fmt.Sprint(reasons) != fmt.Sprint(expected) {
+		t.Errorf("reasons = %v, expected %v", reasons, expected)
+	}
+	if stats := c.Stats(); stats.Evictions != 1 || stats.Misses != 1 {
+		t.Errorf("stats = %+v, expected 1 eviction and 1 miss", stats)
+	}
+}
+
+func TestKeyList(t *testing.T) {
+	l := newKeyList[string]()
+	l.PushFront("a")
+	l.PushFront("b")
+	l.PushFront("c")
+	l.MoveToFront("a")
+
+	if key, _ := l.PopBack(); key != "b" {
+		t.Errorf("PopBack = %q, expected b", key)
+	}
+	if !l.Remove("c") || l.Remove("c") {
+		t.Error("Remove should return true only once")
+	}
+	if l.Len() != 1 || !l.Contains("a") {
+		t.Errorf("list should contain only a, Len is %d", l.Len())
+	}
+	l.PopBack()
+	if _, ok := l.PopBack(); ok {
+		t.Error("PopBack on empty list should return false")
+	}
+}
@@ -0,0 +1,122 @@
+package cache
+
+import (
+	"hash/maphash"
+	"time"
+)
+
+// ShardedLFUCache spreads keys over N independent SyncLFUCache shards, each with its own mutex,
+// so goroutines working with different keys mostly don't wait for each other.
+//
+// The trade-off: LFU order is only kept inside a shard. The victim is the least frequently used
+// entry of the key's shard, not of the whole cache, and total capacity is shards * capacityPerShard.
+// With a decent hash and enough keys shards fill evenly, so in practice it is close to a global LFU.
+type ShardedLFUCache[K comparable, V any] struct {
+	seed   maphash.Seed
+	shards []*SyncLFUCache[K, V]
+}
+
+// Time: O(shards)
+// Space: O(shards) initialization, O(shards * capacityPerShard) when full
+func NewShardedLFU[K comparable, V any](shards int, capacityPerShard int, opts ...Option) *ShardedLFUCache[K, V] {
+	if shards < 1 {
+		shards = 1
+	}
+
+	c := &ShardedLFUCache[K, V]{
+		seed:   maphash.MakeSeed(),
+		shards: make([]*SyncLFUCache[K, V], shards),
+	}
+	for i := range c.shards {
+		c.shards[i] = NewSyncLFU[K, V](capacityPerShard, opts...)
+	}
+	return c
+}
+
+// Time: O(1), maphash of the key
+// Space: O(1)
+func (c *ShardedLFUCache[K, V]) shardFor(key K) *SyncLFUCache[K, V] {
+	h := maphash.Comparable(c.seed, key)
+	return c.shards[h%uint64(len(c.shards))]
+}
+
+// Time: O(1)
+// Space: O(1)
+func (c *ShardedLFUCache[K, V]) Get(key K) (V, bool) {
+	return c.shardFor(key).Get(key)
+}
+
+// Time: O(1)
+// Space: O(1)
+func (c *ShardedLFUCache[K, V]) Put(key K, value V) {
+	c.shardFor(key).Put(key, value)
+}
+
+// Time: O(1)
+// Space: O(1)
+func (c *ShardedLFUCache[K, V]) PutWithTTL(key K, value V, ttl time.Duration) {
+	c.shardFor(key).PutWithTTL(key, value, ttl)
+}
+
+// The cost budget (WithMaxCost) is per shard, same as capacity.
+// Time: O(1) per evicted victim, see LFUCache.PutWithCost
+// Space: O(1)
+func (c *ShardedLFUCache[K, V]) PutWithCost(key K, value V, cost int64) error {
+	return c.shardFor(key).PutWithCost(key, value, cost)
+}
+
+// Time: O(1)
+// Space: O(1)
+func (c *ShardedLFUCache[K, V]) Delete(key K) bool {
+	return c.shardFor(key).Delete(key)
+}
+
+// OnEvict registers the same callback on every shard. Callbacks from different shards
+// may run concurrently, so fn has to be safe for concurrent use.
+// Time: O(shards)
+// Space: O(1)
+func (c *ShardedLFUCache[K, V]) OnEvict(fn func(key K, value V, reason EvictReason)) {
+	for _, shard := range c.shards {
+		shard.OnEvict(fn)
+	}
+}
+
+// PurgeExpired sweeps shards one by one, so other shards stay available meanwhile.
+// Time: O(n)
+// Space: O(1)
+func (c *ShardedLFUCache[K, V]) PurgeExpired() int {
+	removed := 0
+	for _, shard := range c.shards {
+		removed += shard.PurgeExpired()
+	}
+	return removed
+}
+
+// Len locks shards one at a time, so under concurrent writes it is approximate.
+// Time: O(shards)
+// Space: O(1)
+func (c *ShardedLFUCache[K, V]) Len() int {
+	total := 0
+	for _, shard := range c.shards {
+		total += shard.Len()
+	}
+	return total
+}
+
+// Stats sums counters of all shards. Same as Len, it is not an atomic snapshot across shards.
+// Time: O(shards)
+// Space: O(1)
+func (c *ShardedLFUCache[K, V]) Stats() Stats {
+	var total Stats
+	for _, shard := range c.shards {
+		total = total.add(shard.Stats())
+	}
+	return total
+}
+
+// ShardCount returns number of shards.
+// Time: O(1)
+// Space: O(1)
+func (c *ShardedLFUCache[K, V]) ShardCount() int {
+	return len(c.shards)
+}
@@ -0,0 +1,164 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestNewShardedLFU(t *testing.T) {
+	c := NewShardedLFU[string, int](8, 10)
+	if c.ShardCount() != 8 {
+		t.Errorf("ShardCount is %d, expected 8", c.ShardCount())
+	}
+	for i, shard := range c.shards {
+		if shard.cache.capacity != 10 {
+			t.Errorf("shard %d capacity is %d, expected 10", i, shard.cache.capacity)
+		}
+	}
+}
+
+func TestNewShardedLFU_AtLeastOneShard(t *testing.T) {
+	c := NewShardedLFU[string, int](0, 10)
+	if c.ShardCount() != 1 {
+		t.Errorf("ShardCount is %d, expected 1", c.ShardCount())
+	}
+}
+
+func TestShardedLFUCache_PutGet(t *testing.T) {
+	c := NewShardedLFU[int, string](4, 100)
+	for i := range 100 {
+		c.Put(i, fmt.Sprint(i))
+	}
+
+	for i := range 100 {
+		val, ok := c.Get(i)
+		if !ok {
+			t.Errorf("key %d should exist", i)
+			continue
+		}
+		if val != fmt.Sprint(i) {
+			t.Errorf("Get(%d) = %q, expected %q", i, val, fmt.Sprint(i))
+		}
+	}
+}
+
+func TestShardedLFUCache_SameKeySameShard(t *testing.T) {
+	c := NewShardedLFU[string, int](16, 10)
+	if c.shardFor("key") != c.shardFor("key") {
+		t.Error("same key should always map to same shard")
+	}
+}
+
+func TestShardedLFUCache_PerShardCapacity(t *testing.T) {
+	c := NewShardedLFU[int, int](4, 5)
+	for i := range 1000 {
+		c.Put(i, i)
+	}
+
+	for i, shard := range c.shards {
+		if shard.Len() > 5 {
+			t.Errorf("shard %d holds %d entries, expected at most 5", i, shard.Len())
+		}
+	}
+	if c.Len() > 20 {
+		t.Errorf("Len is %d, expected at most 20", c.Len())
+	}
+}
+
+func TestShardedLFUCache_AggregateStats(t *testing.T) {
+	c := NewShardedLFU[int, int](4, 100)
+	for i := range 50 {
+		c.Put(i, i)
+	}
+	for i := range 100 {
+		c.Get(i)
+	}
+
+	stats := c.Stats()
+	if stats.Hits != 50 {
+		t.Errorf("Hits is %d, expected 50", stats.Hits)
+	}
+	if stats.Misses != 50 {
+		t.Errorf("Misses is %d, expected 50", stats.Misses)
+	}
+	if c.Len() != 50 {
+		t.Errorf("Len is %d, expected 50", c.Len())
+	}
+}
+
+// Run with -race: go test -race ./cache
+func TestShardedLFUCache_ConcurrentAccess(t *testing.T) {
+	c := NewShardedLFU[string, int](8, 32)
+
+	var wg sync.WaitGroup
+	for g := range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 2000 {
+				key := fmt.Sprintf("key_%d", (g*31+i)%500)
+				switch i % 3 {
+				case 0:
+					c.Put(key, i)
+				case 1:
+					c.Get(key)
+				default:
+					c.Len()
+					c.Stats()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if c.Len() > 8*32 {
+		t.Errorf("Len is %d, expected at most %d", c.Len(), 8*32)
+	}
+}
+
+func benchmarkKeys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key_%d", i)
+	}
+	return keys
+}
+
+func BenchmarkSyncLFUCache_Parallel(b *testing.B) {
+	c := NewSyncLFU[string, int](1024)
+	keys := benchmarkKeys(4096)
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			key := keys[i%len(keys)]
+			if i%4 == 0 {
+				c.Put(key, i)
+			} else {
+				c.Get(key)
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkShardedLFUCache_Parallel(b *testing.B) {
+	c := NewShardedLFU[string, int](16, 64)
+	keys := benchmarkKeys(4096)
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			key := keys[i%len(keys)]
+			if i%4 == 0 {
+				c.Put(key, i)
+			} else {
+				c.Get(key)
+			}
+			i++
+		}
+	})
+}
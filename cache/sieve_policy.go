@@ -0,0 +1,93 @@
+package cache
+
+import "container/list"
+
+// SIEVEPolicy is SIEVE (Zhang et al., NSDI'24): a FIFO queue with one "visited" bit per key and a moving hand.
+//
+// Hit only sets the bit - nothing is moved, which is why SIEVE is so cheap and lock-friendly.
+// On eviction the hand walks from the tail towards the head, clearing visited bits,
+// and evicts the first key that was not visited since the hand passed it last time.
+// Survivors stay where they are, so new keys near the head are the quickest to be evicted -
+// one-hit wonders leave fast, popular keys stay.
+type SIEVEPolicy[K comparable] struct {
+	capacity int
+	queue    *list.List // front = head (newest), back = tail (oldest)
+	items    map[K]*list.Element
+	hand     *list.Element
+}
+
+type sieveNode[K comparable] struct {
+	key     K
+	visited bool
+}
+
+// Time: O(1)
+// Space: O(capacity) when full
+func NewSIEVEPolicy[K comparable](capacity int) *SIEVEPolicy[K] {
+	return &SIEVEPolicy[K]{
+		capacity: capacity,
+		queue:    list.New(),
+		items:    make(map[K]*list.Element),
+	}
+}
+
+// Time: O(1)
+// Space: O(1)
+func (p *SIEVEPolicy[K]) Hit(key K) {
+	if elem, ok := p.items[key]; ok {
+		elem.Value.(*sieveNode[K]).visited = true
+	}
+}
+
+// Time: O(1) amortized - the hand clears each visited bit once per pass
+// Space: O(1)
+func (p *SIEVEPolicy[K]) Insert(key K) (K, bool) {
+	if p.capacity <= 0 {
+		return key, true
+	}
+
+	var victim K
+	evicted := false
+	if p.queue.Len() >= p.capacity {
+		victim, evicted = p.evict(), true
+	}
+	p.items[key] = p.queue.PushFront(&sieveNode[K]{key: key})
+	return victim, evicted
+}
+
+// Time: O(n) worst case (all keys visited), O(1) amortized
+// Space: O(1)
+func (p *SIEVEPolicy[K]) evict() K {
+	elem := p.hand
+	if elem == nil {
+		elem = p.queue.Back()
+	}
+
+	for elem.Value.(*sieveNode[K]).visited {
+		elem.Value.(*sieveNode[K]).visited = false
+		elem = elem.Prev()
+		if elem == nil {
+			elem = p.queue.Back()
+		}
+	}
+
+	p.hand = elem.Prev()
+	key := elem.Value.(*sieveNode[K]).key
+	p.queue.Remove(elem)
+	delete(p.items, key)
+	return key
+}
+
+// Time: O(1)
+// Space: O(1)
+func (p *SIEVEPolicy[K]) Remove(key K) {
+	elem, ok := p.items[key]
+	if !ok {
+		return
+	}
+	if p.hand == elem {
+		p.hand = elem.Prev()
+	}
+	p.queue.Remove(elem)
+	delete(p.items, key)
+}
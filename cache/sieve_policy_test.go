@@ -0,0 +1,61 @@
+package cache
+
+import "testing"
+
+func TestSIEVEPolicy_VisitedKeysSurvive(t *testing.T) {
+	c := NewPolicyCache[string, int](NewSIEVEPolicy[string](3))
+	c.Put("a", 1)
+	c.Put("b", 2)
+	c.Put("c", 3)
+	c.Get("a")
+	c.Put("d", 4)
+
+	if _, ok := c.values["b"]; ok {
+		t.Error("b should be evicted: a is visited, b is the next unvisited from the tail")
+	}
+	if _, ok := c.values["a"]; !ok {
+		t.Error("a should survive thanks to visited bit")
+	}
+}
+
+func TestSIEVEPolicy_HitDoesNotMoveKey(t *testing.T) {
+	p := NewSIEVEPolicy[string](3)
+	p.Insert("a")
+	p.Insert("b")
+	p.Hit("a")
+
+	if p.queue.Back().Value.(*sieveNode[string]).key != "a" {
+		t.Error("hit should only mark the key visited, not move it")
+	}
+}
+
+func TestSIEVEPolicy_HandKeepsPosition(t *testing.T) {
+	p := NewSIEVEPolicy[string](3)
+	p.Insert("a")
+	p.Insert("b")
+	p.Insert("c")
+	p.Hit("a")
+	p.Hit("b")
+
+	if victim, _ := p.Insert("d"); victim != "c" {
+		t.Errorf("first victim is %q, expected c", victim)
+	}
+	// hand walked past a and b clearing their bits and stopped at c,
+	// so the next pass starts from the tail again with nothing visited
+	if victim, _ := p.Insert("e"); victim != "a" {
+		t.Errorf("second victim is %q, expected a", victim)
+	}
+}
+
+func TestSIEVEPolicy_RemoveHand(t *testing.T) {
+	p := NewSIEVEPolicy[string](2)
+	p.Insert("a")
+	p.Insert("b")
+	p.Insert("c")
+	p.Remove(p.hand.Value.(*sieveNode[string]).key)
+	p.Insert("d")
+
+	if p.queue.Len() != 2 {
+		t.Errorf("queue len is %d, expected 2", p.queue.Len())
+	}
+}
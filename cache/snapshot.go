@@ -0,0 +1,235 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"slices"
+	"time"
+)
+
+// Snapshot format (all integers are varints unless said otherwise):
+//
+//	magic     "LFUS"
+//	version   1 byte
+//	codec     1 byte length + codec name
+//	age       LFU-DA age, 0 without dynamic aging
+//	count     number of entries
+//	entries   freq, expiresAt (unix nanos, 0 = never), cost, key length + key bytes, value length + value bytes
+//	checksum  CRC-32 (IEEE) of everything above, 4 bytes big endian
+//
+// Entries go bucket by bucket in ascending freq order, each bucket from the oldest entry to the newest.
+// Restore links them back in the same order, so entries land in their old buckets with the same LRU order,
+// and if the target cache is smaller, the least valuable entries are the ones that get evicted.
+
+const (
+	snapshotMagic   = "LFUS"
+	snapshotVersion = 1
+)
+
+var (
+	ErrSnapshotCorrupted = errors.New("cache snapshot is corrupted")
+	ErrSnapshotVersion   = errors.New("unsupported cache snapshot version")
+	ErrSnapshotCodec     = errors.New("cache snapshot was written with another codec")
+)
+
+// Snapshot writes all live (not expired) entries with their frequencies to w.
+// The snapshot is built in memory first, the checksum needs the whole payload anyway.
+// Time: O(n + F log F)
+// Space: O(n) for the encoded snapshot
+func (c *LFUCache[K, V]) Snapshot(w io.Writer, codec Codec) error {
+	var buf bytes.Buffer
+	buf.WriteString(snapshotMagic)
+	buf.WriteByte(snapshotVersion)
+	buf.WriteByte(byte(len(codec.Name())))
+	buf.WriteString(codec.Name())
+	buf.Write(binary.AppendVarint(nil, int64(c.age)))
+
+	now := c.clock()
+	var live []*cacheEntry[K, V]
+	freqs := make([]int, 0, len(c.freqMap))
+	for freq := range c.freqMap {
+		freqs = append(freqs, freq)
+	}
+	slices.Sort(freqs)
+	for _, freq := range freqs {
+		for elem := c.freqMap[freq].Back(); elem != nil; elem = elem.Prev() {
+			if e := elem.Value.(*cacheEntry[K, V]); !e.expired(now) {
+				live = append(live, e)
+			}
+		}
+	}
+
+	buf.Write(binary.AppendUvarint(nil, uint64(len(live))))
+	for _, e := range live {
+		key, err := codec.Marshal(e.key)
+		if err != nil {
+			return fmt.Errorf("encode key: %w", err)
+		}
+		value, err := codec.Marshal(e.value)
+		if err != nil {
+			return fmt.Errorf("encode value: %w", err)
+		}
+
+		var expiresAt int64
+		if !e.expiresAt.IsZero() {
+			expiresAt = e.expiresAt.UnixNano()
+		}
+
+		buf.Write(binary.AppendUvarint(nil, uint64(e.freq)))
+		buf.Write(binary.AppendVarint(nil, expiresAt))
+		buf.Write(binary.AppendVarint(nil, e.cost))
+		buf.Write(binary.AppendUvarint(nil, uint64(len(key))))
+		buf.Write(key)
+		buf.Write(binary.AppendUvarint(nil, uint64(len(value))))
+		buf.Write(value)
+	}
+
+	buf.Write(binary.BigEndian.AppendUint32(nil, crc32.ChecksumIEEE(buf.Bytes())))
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// Restore reads a snapshot made by Snapshot and puts its entries back into their frequency buckets.
+// Entries are added on top of the current content (same keys are overwritten), entries expired
+// in the meantime are skipped. Nothing is changed unless the whole snapshot is valid.
+// The LFU-DA age is adopted only by a cache with dynamic aging.
+// Time: O(n), plus O(F) per re-linked bucket when the cache is smaller than the snapshot
+// Space: O(n) for the raw snapshot and decoded entries
+func (c *LFUCache[K, V]) Restore(r io.Reader, codec Codec) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if len(data) < len(snapshotMagic)+1+4 || string(data[:len(snapshotMagic)]) != snapshotMagic {
+		return ErrSnapshotCorrupted
+	}
+
+	payload, sum := data[:len(data)-4], binary.BigEndian.Uint32(data[len(data)-4:])
+	if crc32.ChecksumIEEE(payload) != sum {
+		return fmt.Errorf("%w: checksum mismatch", ErrSnapshotCorrupted)
+	}
+
+	rd := snapshotReader{data: payload[len(snapshotMagic):]}
+	if version := rd.readByte(); version != snapshotVersion {
+		return fmt.Errorf("%w: %d", ErrSnapshotVersion, version)
+	}
+	if name := string(rd.readBytes(uint64(rd.readByte()))); rd.err == nil && name != codec.Name() {
+		return fmt.Errorf("%w: snapshot uses %q, got %q", ErrSnapshotCodec, name, codec.Name())
+	}
+	age := rd.readVarint()
+	count := rd.readUvarint()
+
+	entries := make([]*cacheEntry[K, V], 0, min(count, uint64(len(payload))))
+	for i := uint64(0); i < count && rd.err == nil; i++ {
+		e := &cacheEntry[K, V]{
+			freq: int(rd.readUvarint()),
+		}
+		if expiresAt := rd.readVarint(); expiresAt != 0 {
+			e.expiresAt = time.Unix(0, expiresAt)
+		}
+		e.cost = rd.readVarint()
+		key := rd.readBytes(rd.readUvarint())
+		value := rd.readBytes(rd.readUvarint())
+		if rd.err != nil {
+			break
+		}
+		if e.cost < 0 {
+			// PutWithCost never lets a negative cost in, it would corrupt totalCost
+			return fmt.Errorf("%w: negative cost", ErrSnapshotCorrupted)
+		}
+
+		if err := codec.Unmarshal(key, &e.key); err != nil {
+			return fmt.Errorf("decode key: %w", err)
+		}
+		if err := codec.Unmarshal(value, &e.value); err != nil {
+			return fmt.Errorf("decode value: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	if rd.err != nil || len(rd.data) != 0 {
+		return ErrSnapshotCorrupted
+	}
+
+	if c.capacity <= 0 {
+		return nil
+	}
+	now := c.clock()
+	for _, e := range entries {
+		if e.expired(now) || e.freq < 1 {
+			continue
+		}
+		if c.maxCost > 0 && e.cost > c.maxCost {
+			continue
+		}
+		if item, ok := c.cache[e.key]; ok {
+			c.notifyEvict(item, EvictReplaced)
+			c.removeElement(item)
+		}
+		c.makeRoom(e.cost)
+		c.link(e)
+	}
+	// The age is taken only by an LFU-DA cache, and only after linking: link relies on
+	// every entry having freq > age when minFreq is unknown, a jump in the middle would break that.
+	if c.aging == AgingDynamic {
+		c.age = max(c.age, int(age))
+	}
+	return nil
+}
+
+// snapshotReader decodes varints and byte strings, remembering the first error
+// so Restore can check it once instead of after every field.
+type snapshotReader struct {
+	data []byte
+	err  error
+}
+
+func (r *snapshotReader) readByte() byte {
+	b := r.readBytes(1)
+	if r.err != nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (r *snapshotReader) readBytes(n uint64) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n > uint64(len(r.data)) {
+		r.err = ErrSnapshotCorrupted
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *snapshotReader) readUvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		r.err = ErrSnapshotCorrupted
+		return 0
+	}
+	r.data = r.data[n:]
+	return v
+}
+
+func (r *snapshotReader) readVarint() int64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(r.data)
+	if n <= 0 {
+		r.err = ErrSnapshotCorrupted
+		return 0
+	}
+	r.data = r.data[n:]
+	return v
+}
@@ -0,0 +1,311 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"testing"
+	"time"
+)
+
+func freqOf[K comparable, V any](c *LFUCache[K, V], key K) int {
+	elem, ok := c.cache[key]
+	if !ok {
+		return 0
+	}
+	return elem.Value.(*cacheEntry[K, V]).freq
+}
+
+func TestSnapshot_RestoreKeepsValuesAndFrequencies(t *testing.T) {
+	for _, codec := range []Codec{GobCodec{}, JSONCodec{}} {
+		t.Run(codec.Name(), func(t *testing.T) {
+			src := NewLFU[string, int](10)
+			src.Put("a", 1)
+			src.Put("b", 2)
+			src.Put("c", 3)
+			for range 4 {
+				src.Get("a")
+			}
+			src.Get("b")
+
+			var buf bytes.Buffer
+			if err := src.Snapshot(&buf, codec); err != nil {
+				t.Fatalf("Snapshot returned %v", err)
+			}
+
+			dst := NewLFU[string, int](10)
+			if err := dst.Restore(&buf, codec); err != nil {
+				t.Fatalf("Restore returned %v", err)
+			}
+
+			for key, freq := range map[string]int{"a": 5, "b": 2, "c": 1} {
+				if got := freqOf(dst, key); got != freq {
+					t.Errorf("%s.freq is %d, expected %d", key, got, freq)
+				}
+			}
+			if dst.minFreq != 1 {
+				t.Errorf("minFreq is %d, expected 1", dst.minFreq)
+			}
+			if val, _ := dst.Get("b"); val != 2 {
+				t.Errorf("Get(b) = %d, expected 2", val)
+			}
+		})
+	}
+}
+
+func TestSnapshot_StructValues(t *testing.T) {
+	type session struct {
+		User  string
+		Roles []string
+	}
+
+	src := NewLFU[int, session](10)
+	src.Put(1, session{User: "alice", Roles: []string{"admin"}})
+
+	var buf bytes.Buffer
+	if err := src.Snapshot(&buf, GobCodec{}); err != nil {
+		t.Fatal(err)
+	}
+	dst := NewLFU[int, session](10)
+	if err := dst.Restore(&buf, GobCodec{}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, ok := dst.Get(1)
+	if !ok || got.User != "alice" || len(got.Roles) != 1 || got.Roles[0] != "admin" {
+		t.Errorf("Get(1) = %+v, %v, expected alice/admin", got, ok)
+	}
+}
+
+func TestSnapshot_KeepsLRUOrderInBucket(t *testing.T) {
+	src := NewLFU[string, int](3)
+	src.Put("oldest", 1)
+	src.Put("middle", 2)
+	src.Put("newest", 3)
+
+	var buf bytes.Buffer
+	src.Snapshot(&buf, JSONCodec{})
+	dst := NewLFU[string, int](3)
+	dst.Restore(&buf, JSONCodec{})
+
+	dst.Put("new", 4)
+	if _, ok := dst.cache["oldest"]; ok {
+		t.Error("oldest should be evicted first after restore, as it would be before")
+	}
+}
+
+func TestSnapshot_RestoreIntoSmallerCacheDropsLeastFrequent(t *testing.T) {
+	src := NewLFU[string, int](10)
+	for i := range 10 {
+		key := fmt.Sprint("k", i)
+		src.Put(key, i)
+		for range i {
+			src.Get(key)
+		}
+	}
+
+	var buf bytes.Buffer
+	src.Snapshot(&buf, GobCodec{})
+	dst := NewLFU[string, int](3)
+	if err := dst.Restore(&buf, GobCodec{}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"k7", "k8", "k9"} {
+		if _, ok := dst.cache[key]; !ok {
+			t.Errorf("%s should survive restore into smaller cache", key)
+		}
+	}
+	if dst.Len() != 3 {
+		t.Errorf("Len is %d, expected 3", dst.Len())
+	}
+}
+
+func TestSnapshot_SkipsExpiredEntries(t *testing.T) {
+	clock := newFakeClock()
+	src := NewLFU[string, int](10, WithClock(clock.Now))
+	src.PutWithTTL("short", 1, time.Second)
+	src.PutWithTTL("long", 2, time.Hour)
+	src.Put("forever", 3)
+
+	var buf bytes.Buffer
+	src.Snapshot(&buf, JSONCodec{})
+
+	clock.Advance(2 * time.Second)
+	dst := NewLFU[string, int](10, WithClock(clock.Now))
+	dst.Restore(bytes.NewReader(buf.Bytes()), JSONCodec{})
+
+	if _, ok := dst.Get("short"); ok {
+		t.Error("short expired before restore and should be skipped")
+	}
+	if _, ok := dst.Get("long"); !ok {
+		t.Error("long should be restored with its deadline")
+	}
+
+	clock.Advance(time.Hour)
+	if _, ok := dst.Get("long"); ok {
+		t.Error("restored entry should keep its original deadline")
+	}
+	if _, ok := dst.Get("forever"); !ok {
+		t.Error("forever should never expire")
+	}
+}
+
+func TestSnapshot_KeepsCostAndAge(t *testing.T) {
+	src := NewLFU[string, int](2, WithDynamicAging(), WithMaxCost(100))
+	src.PutWithCost("a", 1, 30)
+	src.PutWithCost("b", 2, 40)
+	src.Get("b")
+	src.PutWithCost("c", 3, 50)
+
+	var buf bytes.Buffer
+	src.Snapshot(&buf, GobCodec{})
+	dst := NewLFU[string, int](2, WithDynamicAging(), WithMaxCost(100))
+	dst.Restore(&buf, GobCodec{})
+
+	if dst.Cost() != src.Cost() {
+		t.Errorf("Cost is %d, expected %d", dst.Cost(), src.Cost())
+	}
+	if dst.age != src.age {
+		t.Errorf("age is %d, expected %d", dst.age, src.age)
+	}
+}
+
+func TestSnapshot_PlainCacheIgnoresAge(t *testing.T) {
+	src := NewLFU[string, int](1, WithDynamicAging())
+	src.Put("a", 1)
+	src.Get("a")
+	src.Put("b", 2) // evicts a at priority 2, b starts at 3
+
+	var buf bytes.Buffer
+	src.Snapshot(&buf, GobCodec{})
+
+	dst := NewLFU[string, int](3)
+	dst.Put("w", 0)
+	dst.Get("w")
+	if err := dst.Restore(&buf, GobCodec{}); err != nil {
+		t.Fatalf("Restore returned %v", err)
+	}
+	if dst.age != 0 {
+		t.Errorf("age is %d, expected 0 for a cache without dynamic aging", dst.age)
+	}
+
+	dst.Put("n", 1)
+	if freqOf(dst, "n") != 1 || dst.minFreq != 1 {
+		t.Errorf("new entry freq %d, minFreq %d; expected 1, 1", freqOf(dst, "n"), dst.minFreq)
+	}
+	dst.Put("z", 2) // full: n is the least frequently used
+	if _, ok := dst.cache["n"]; ok {
+		t.Error("n should be evicted first")
+	}
+	if _, ok := dst.cache["w"]; !ok {
+		t.Error("w (freq 2) should stay")
+	}
+}
+
+func TestRestore_RejectsNegativeCost(t *testing.T) {
+	codec := GobCodec{}
+	key, _ := codec.Marshal("k")
+	value, _ := codec.Marshal(1)
+
+	var buf bytes.Buffer
+	buf.WriteString(snapshotMagic)
+	buf.WriteByte(snapshotVersion)
+	buf.WriteByte(byte(len(codec.Name())))
+	buf.WriteString(codec.Name())
+	buf.Write(binary.AppendVarint(nil, 0))  // age
+	buf.Write(binary.AppendUvarint(nil, 1)) // count
+	buf.Write(binary.AppendUvarint(nil, 1)) // freq
+	buf.Write(binary.AppendVarint(nil, 0))  // expiresAt
+	buf.Write(binary.AppendVarint(nil, -5)) // cost
+	buf.Write(binary.AppendUvarint(nil, uint64(len(key))))
+	buf.Write(key)
+	buf.Write(binary.AppendUvarint(nil, uint64(len(value))))
+	buf.Write(value)
+	buf.Write(crcBytes(buf.Bytes()))
+
+	dst := NewLFU[string, int](10, WithMaxCost(100))
+	if err := dst.Restore(&buf, codec); !errors.Is(err, ErrSnapshotCorrupted) {
+		t.Errorf("Restore returned %v, expected ErrSnapshotCorrupted", err)
+	}
+	if dst.Len() != 0 || dst.Cost() != 0 {
+		t.Errorf("rejected snapshot left %d entries, cost %d", dst.Len(), dst.Cost())
+	}
+}
+
+func TestRestore_DetectsCorruption(t *testing.T) {
+	src := NewLFU[string, int](10)
+	src.Put("a", 1)
+	src.Put("b", 2)
+
+	var buf bytes.Buffer
+	src.Snapshot(&buf, GobCodec{})
+	data := buf.Bytes()
+
+	flipped := bytes.Clone(data)
+	flipped[len(flipped)/2] ^= 0xff
+	truncated := data[:len(data)-3]
+
+	for name, input := range map[string][]byte{"flipped": flipped, "truncated": truncated, "empty": nil, "garbage": []byte("not a snapshot")} {
+		dst := NewLFU[string, int](10)
+		if err := dst.Restore(bytes.NewReader(input), GobCodec{}); !errors.Is(err, ErrSnapshotCorrupted) {
+			t.Errorf("%s: Restore returned %v, expected ErrSnapshotCorrupted", name, err)
+		}
+		if dst.Len() != 0 {
+			t.Errorf("%s: corrupted snapshot must not change the cache", name)
+		}
+	}
+}
+
+func TestRestore_CodecMismatch(t *testing.T) {
+	src := NewLFU[string, int](10)
+	src.Put("a", 1)
+
+	var buf bytes.Buffer
+	src.Snapshot(&buf, GobCodec{})
+
+	dst := NewLFU[string, int](10)
+	if err := dst.Restore(&buf, JSONCodec{}); !errors.Is(err, ErrSnapshotCodec) {
+		t.Errorf("Restore returned %v, expected ErrSnapshotCodec", err)
+	}
+}
+
+func TestRestore_UnsupportedVersion(t *testing.T) {
+	src := NewLFU[string, int](10)
+	var buf bytes.Buffer
+	src.Snapshot(&buf, GobCodec{})
+
+	data := buf.Bytes()
+	data[len(snapshotMagic)] = 99
+	// fix up the checksum so only the version is wrong
+	payload := data[:len(data)-4]
+	copy(data[len(data)-4:], crcBytes(payload))
+
+	dst := NewLFU[string, int](10)
+	if err := dst.Restore(bytes.NewReader(data), GobCodec{}); !errors.Is(err, ErrSnapshotVersion) {
+		t.Errorf("Restore returned %v, expected ErrSnapshotVersion", err)
+	}
+}
+
+func TestSyncLFUCache_SnapshotRestore(t *testing.T) {
+	src := NewSyncLFU[string, int](10)
+	src.Put("a", 1)
+
+	var buf bytes.Buffer
+	if err := src.Snapshot(&buf, JSONCodec{}); err != nil {
+		t.Fatal(err)
+	}
+	dst := NewSyncLFU[string, int](10)
+	if err := dst.Restore(&buf, JSONCodec{}); err != nil {
+		t.Fatal(err)
+	}
+	if val, ok := dst.Get("a"); !ok || val != 1 {
+		t.Errorf("Get(a) = %d, %v, expected 1, true", val, ok)
+	}
+}
+
+func crcBytes(payload []byte) []byte {
+	return binary.BigEndian.AppendUint32(nil, crc32.ChecksumIEEE(payload))
+}
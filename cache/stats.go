@@ -0,0 +1,59 @@
+package cache
+
+// EvictReason tells OnEvict callbacks why the entry left the cache.
+type EvictReason int
+
+const (
+	// EvictCapacity - cache was full and the entry was chosen as a victim.
+	EvictCapacity EvictReason = iota
+	// EvictDeleted - caller removed the entry explicitly with Delete.
+	EvictDeleted
+	// EvictExpired - entry outlived its TTL.
+	EvictExpired
+	// EvictReplaced - Put stored a new value for the same key, the old value is reported.
+	EvictReplaced
+)
+
+func (r EvictReason) String() string {
+	switch r {
+	case EvictCapacity:
+		return "capacity"
+	case EvictDeleted:
+		return "deleted"
+	case EvictExpired:
+		return "expired"
+	case EvictReplaced:
+		return "replaced"
+	default:
+		return "unknown"
+	}
+}
+
+// Stats is a point-in-time snapshot of cache counters.
+// Evictions counts entries dropped by the cache itself (capacity pressure or expiry),
+// not the ones removed on caller's request.
+type Stats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+}
+
+// HitRatio is Hits / (Hits + Misses), 0 when there were no lookups yet.
+// Time: O(1)
+// Space: O(1)
+func (s Stats) HitRatio() float64 {
+	lookups := s.Hits + s.Misses
+	if lookups == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(lookups)
+}
+
+// add is used to aggregate stats of several shards into one snapshot.
+func (s Stats) add(other Stats) Stats {
+	return Stats{
+		Hits:      s.Hits + other.Hits,
+		Misses:    s.Misses + other.Misses,
+		Evictions: s.Evictions + other.Evictions,
+	}
+}
@@ -0,0 +1,76 @@
+package cache
+
+import "sync"
+
+// Store is the slow backing storage behind WriteBackCache: a database, a file, another service.
+// Load must return ErrKeyNotFound (maybe wrapped) for missing keys.
+type Store[K comparable, V any] interface {
+	Load(key K) (V, error)
+	Save(key K, value V) error
+	Delete(key K) error
+}
+
+// MemoryStore is a map-backed Store, mostly meant as a fake for tests.
+type MemoryStore[K comparable, V any] struct {
+	mu    sync.Mutex
+	data  map[K]V
+	saves int
+}
+
+// Time: O(1)
+// Space: O(1)
+func NewMemoryStore[K comparable, V any]() *MemoryStore[K, V] {
+	return &MemoryStore[K, V]{data: make(map[K]V)}
+}
+
+// Time: O(1)
+// Space: O(1)
+func (s *MemoryStore[K, V]) Load(key K) (V, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value, ok := s.data[key]
+	if !ok {
+		return value, ErrKeyNotFound
+	}
+	return value, nil
+}
+
+// Time: O(1)
+// Space: O(1)
+func (s *MemoryStore[K, V]) Save(key K, value V) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data[key] = value
+	s.saves++
+	return nil
+}
+
+// Time: O(1)
+// Space: O(1)
+func (s *MemoryStore[K, V]) Delete(key K) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.data, key)
+	return nil
+}
+
+// Saves returns how many times Save was called, handy to check that write-back batches writes.
+// Time: O(1)
+// Space: O(1)
+func (s *MemoryStore[K, V]) Saves() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.saves
+}
+
+// Len returns number of stored keys.
+// Time: O(1)
+// Space: O(1)
+func (s *MemoryStore[K, V]) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.data)
+}
@@ -0,0 +1,114 @@
+package cache
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// SyncLFUCache is the simplest thread-safe LFUCache: one mutex around everything.
+// Note that even Get needs an exclusive lock, because it moves the entry into the next
+// frequency bucket, so sync.RWMutex would not help here.
+//
+// Under heavy parallel load this single mutex becomes the bottleneck,
+// see ShardedLFUCache in sharded_lfu_cache.go.
+type SyncLFUCache[K comparable, V any] struct {
+	mu    sync.Mutex
+	cache *LFUCache[K, V]
+}
+
+// Time: O(1)
+// Space: O(1) initialization, O(capacity) when full
+func NewSyncLFU[K comparable, V any](capacity int, opts ...Option) *SyncLFUCache[K, V] {
+	return &SyncLFUCache[K, V]{cache: NewLFU[K, V](capacity, opts...)}
+}
+
+// Time: O(1)
+// Space: O(1)
+func (c *SyncLFUCache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cache.Get(key)
+}
+
+// Time: O(1)
+// Space: O(1)
+func (c *SyncLFUCache[K, V]) Put(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cache.Put(key, value)
+}
+
+// Time: O(1)
+// Space: O(1)
+func (c *SyncLFUCache[K, V]) PutWithTTL(key K, value V, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cache.PutWithTTL(key, value, ttl)
+}
+
+// Time: O(1) per evicted victim, see LFUCache.PutWithCost
+// Space: O(1)
+func (c *SyncLFUCache[K, V]) PutWithCost(key K, value V, cost int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cache.PutWithCost(key, value, cost)
+}
+
+// Time: O(1)
+// Space: O(1)
+func (c *SyncLFUCache[K, V]) Delete(key K) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cache.Delete(key)
+}
+
+// OnEvict callback runs while the mutex is held, so it must not call back into the cache.
+// Time: O(1)
+// Space: O(1)
+func (c *SyncLFUCache[K, V]) OnEvict(fn func(key K, value V, reason EvictReason)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cache.OnEvict(fn)
+}
+
+// Time: O(n)
+// Space: O(1)
+func (c *SyncLFUCache[K, V]) PurgeExpired() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cache.PurgeExpired()
+}
+
+// Time: O(1)
+// Space: O(1)
+func (c *SyncLFUCache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cache.Len()
+}
+
+// Time: O(1)
+// Space: O(1)
+func (c *SyncLFUCache[K, V]) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cache.Stats()
+}
+
+// Snapshot holds the lock for the whole encoding, so the snapshot is consistent.
+// Time: O(n + F log F)
+// Space: O(n)
+func (c *SyncLFUCache[K, V]) Snapshot(w io.Writer, codec Codec) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cache.Snapshot(w, codec)
+}
+
+// Time: O(n)
+// Space: O(n)
+func (c *SyncLFUCache[K, V]) Restore(r io.Reader, codec Codec) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cache.Restore(r, codec)
+}
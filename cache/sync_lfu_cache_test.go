@@ -0,0 +1,50 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestSyncLFUCache_PutGet(t *testing.T) {
+	c := NewSyncLFU[string, int](2)
+	c.Put("a", 1)
+	c.Put("b", 2)
+	c.Get("a")
+	c.Put("c", 3)
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("b should be evicted (least frequently used)")
+	}
+	if val, ok := c.Get("a"); !ok || val != 1 {
+		t.Errorf("Get(a) = %d, %v, expected 1, true", val, ok)
+	}
+	if c.Len() != 2 {
+		t.Errorf("Len is %d, expected 2", c.Len())
+	}
+}
+
+func TestSyncLFUCache_ConcurrentAccess(t *testing.T) {
+	c := NewSyncLFU[string, int](100)
+
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 1000 {
+				key := fmt.Sprintf("key_%d", (g*1000+i)%150)
+				c.Put(key, i)
+				c.Get(key)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if c.Len() > 100 {
+		t.Errorf("Len is %d, expected at most 100", c.Len())
+	}
+	if stats := c.Stats(); stats.Hits+stats.Misses != 8000 {
+		t.Errorf("Hits+Misses is %d, expected 8000", stats.Hits+stats.Misses)
+	}
+}
@@ -0,0 +1,145 @@
+package cache
+
+import "container/list"
+
+// TinyLFUCache is W-TinyLFU (Einziger, Friedman, Manes) built around LFUCache.
+//
+// Plain LFU has two weak spots:
+//  1. every new key goes straight into freqMap[1], so a burst of one-hit wonders
+//     keeps evicting each other and also the few useful newcomers;
+//  2. a new key always wins against the victim, even if the victim is accessed far more often.
+//
+// Here a new key first lands in a small LRU "window" (about 1% of capacity), so recent bursts are still served.
+// When the window overflows, its LRU entry becomes a candidate for the main LFUCache, and the admission filter
+// compares approximate frequencies (frequencySketch) of the candidate and of the current minFreq victim.
+// The candidate gets in only if it is accessed more often than the victim, otherwise the candidate is dropped.
+// Frequencies are remembered for keys which are not in the cache too, so a key that keeps coming back
+// eventually earns its place.
+//
+// All operations are still O(1): window is a plain LRU, main cache keeps LFUCache's frequency buckets.
+type TinyLFUCache[K comparable, V any] struct {
+	windowCap int
+	window    *list.List
+	windowMap map[K]*list.Element
+	main      *LFUCache[K, V]
+	sketch    *frequencySketch[K]
+	stats     Stats
+}
+
+type windowEntry[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+// Time: O(capacity) for sketch allocation
+// Space: O(capacity)
+func NewTinyLFU[K comparable, V any](capacity int) *TinyLFUCache[K, V] {
+	windowCap := max(1, capacity/100)
+	if capacity <= 1 {
+		// no room for both window and main cache, window alone behaves as LRU
+		windowCap = max(0, capacity)
+	}
+
+	return &TinyLFUCache[K, V]{
+		windowCap: windowCap,
+		window:    list.New(),
+		windowMap: make(map[K]*list.Element),
+		main:      NewLFU[K, V](capacity - windowCap),
+		sketch:    newFrequencySketch[K](capacity),
+	}
+}
+
+// Time: O(1)
+// Space: O(1)
+func (c *TinyLFUCache[K, V]) Get(key K) (V, bool) {
+	c.sketch.increment(key)
+
+	if elem, ok := c.windowMap[key]; ok {
+		c.window.MoveToFront(elem)
+		c.stats.Hits++
+		return elem.Value.(*windowEntry[K, V]).value, true
+	}
+
+	if value, ok := c.main.Get(key); ok {
+		c.stats.Hits++
+		return value, true
+	}
+
+	c.stats.Misses++
+	var zero V
+	return zero, false
+}
+
+// Time: O(1)
+// Space: O(1)
+func (c *TinyLFUCache[K, V]) Put(key K, value V) {
+	if c.windowCap <= 0 {
+		return
+	}
+
+	c.sketch.increment(key)
+
+	if elem, ok := c.windowMap[key]; ok {
+		elem.Value.(*windowEntry[K, V]).value = value
+		c.window.MoveToFront(elem)
+		return
+	}
+
+	if _, ok := c.main.cache[key]; ok {
+		c.main.Put(key, value)
+		return
+	}
+
+	c.windowMap[key] = c.window.PushFront(&windowEntry[K, V]{key: key, value: value})
+	if c.window.Len() > c.windowCap {
+		oldest := c.window.Back()
+		c.window.Remove(oldest)
+		candidate := oldest.Value.(*windowEntry[K, V])
+		delete(c.windowMap, candidate.key)
+		c.admit(candidate)
+	}
+}
+
+// admit decides whether the window's LRU candidate may replace the main cache's LFU victim.
+// Ties go to the victim: a candidate has to be strictly more popular to get in.
+// Time: O(1)
+// Space: O(1)
+func (c *TinyLFUCache[K, V]) admit(candidate *windowEntry[K, V]) {
+	if c.main.Len() < c.main.capacity {
+		c.main.Put(candidate.key, candidate.value)
+		return
+	}
+
+	victim, ok := c.main.victim()
+	if ok && c.sketch.estimate(candidate.key) > c.sketch.estimate(victim.key) {
+		c.main.Put(candidate.key, candidate.value)
+		return
+	}
+	c.stats.Evictions++
+}
+
+// Time: O(1)
+// Space: O(1)
+func (c *TinyLFUCache[K, V]) Delete(key K) bool {
+	if elem, ok := c.windowMap[key]; ok {
+		c.window.Remove(elem)
+		delete(c.windowMap, key)
+		return true
+	}
+	return c.main.Delete(key)
+}
+
+// Time: O(1)
+// Space: O(1)
+func (c *TinyLFUCache[K, V]) Len() int {
+	return c.window.Len() + c.main.Len()
+}
+
+// Evictions include both candidates rejected by the admission filter and victims evicted from the main cache.
+// Time: O(1)
+// Space: O(1)
+func (c *TinyLFUCache[K, V]) Stats() Stats {
+	stats := c.stats
+	stats.Evictions += c.main.Stats().Evictions
+	return stats
+}
@@ -0,0 +1,168 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestNewTinyLFU_Sizes(t *testing.T) {
+	c := NewTinyLFU[string, int](1000)
+	if c.windowCap != 10 {
+		t.Errorf("windowCap is %d, expected 10 (1%%)", c.windowCap)
+	}
+	if c.main.capacity != 990 {
+		t.Errorf("main capacity is %d, expected 990", c.main.capacity)
+	}
+
+	small := NewTinyLFU[string, int](10)
+	if small.windowCap != 1 || small.main.capacity != 9 {
+		t.Errorf("window/main is %d/%d, expected 1/9", small.windowCap, small.main.capacity)
+	}
+}
+
+func TestTinyLFUCache_PutGet(t *testing.T) {
+	c := NewTinyLFU[string, int](100)
+	for i := range 50 {
+		c.Put(fmt.Sprint(i), i)
+	}
+
+	for i := range 50 {
+		val, ok := c.Get(fmt.Sprint(i))
+		if !ok {
+			t.Errorf("key %d should exist", i)
+			continue
+		}
+		if val != i {
+			t.Errorf("Get(%d) = %d, expected %d", i, val, i)
+		}
+	}
+	if c.Len() != 50 {
+		t.Errorf("Len is %d, expected 50", c.Len())
+	}
+}
+
+func TestTinyLFUCache_UpdateExistingKey(t *testing.T) {
+	c := NewTinyLFU[string, int](10)
+	c.Put("a", 1)
+	c.Put("b", 2)
+	c.Put("a", 10)
+	c.Put("b", 20)
+
+	if val, _ := c.Get("a"); val != 10 {
+		t.Errorf("Get(a) = %d, expected 10", val)
+	}
+	if val, _ := c.Get("b"); val != 20 {
+		t.Errorf("Get(b) = %d, expected 20", val)
+	}
+}
+
+func TestTinyLFUCache_ZeroCapacity(t *testing.T) {
+	c := NewTinyLFU[string, int](0)
+	c.Put("a", 1)
+	if _, ok := c.Get("a"); ok {
+		t.Error("zero capacity cache should not store anything")
+	}
+}
+
+func TestTinyLFUCache_NeverExceedsCapacity(t *testing.T) {
+	c := NewTinyLFU[int, int](50)
+	for i := range 1000 {
+		c.Put(i, i)
+		if c.Len() > 50 {
+			t.Fatalf("Len is %d after %d puts, expected at most 50", c.Len(), i+1)
+		}
+	}
+}
+
+func TestTinyLFUCache_RecentKeyServedFromWindow(t *testing.T) {
+	c := NewTinyLFU[string, int](100)
+	c.Put("fresh", 1)
+
+	if _, ok := c.windowMap["fresh"]; !ok {
+		t.Fatal("new key should land in the window first")
+	}
+	if _, ok := c.Get("fresh"); !ok {
+		t.Error("fresh key should be served from window")
+	}
+}
+
+func TestTinyLFUCache_BurstDoesNotFlushHotKeys(t *testing.T) {
+	c := NewTinyLFU[string, int](100)
+
+	for round := range 3 {
+		for i := range 90 {
+			key := fmt.Sprintf("hot_%d", i)
+			if round == 0 {
+				c.Put(key, i)
+			} else {
+				c.Get(key)
+			}
+		}
+	}
+
+	for i := range 5000 {
+		c.Put(fmt.Sprintf("burst_%d", i), i)
+	}
+
+	survived := 0
+	for i := range 90 {
+		if _, ok := c.main.cache[fmt.Sprintf("hot_%d", i)]; ok {
+			survived++
+		}
+	}
+	if survived < 85 {
+		t.Errorf("only %d/90 hot keys survived a burst of one-hit keys", survived)
+	}
+	if c.Stats().Evictions == 0 {
+		t.Error("burst candidates should be rejected by admission filter")
+	}
+}
+
+func TestTinyLFUCache_FrequentCandidateIsAdmitted(t *testing.T) {
+	c := NewTinyLFU[string, int](10)
+
+	for i := range 9 {
+		c.Put(fmt.Sprintf("k%d", i), i)
+	}
+	c.Put("filler", 0)
+
+	// "popular" is seen many times before it is even stored, sketch remembers it
+	for range 5 {
+		c.Get("popular")
+	}
+	c.Put("popular", 42)
+	c.Put("pusher", 0) // pushes "popular" out of the 1-entry window
+
+	if _, ok := c.main.cache["popular"]; !ok {
+		t.Error("popular candidate should win against a one-hit victim")
+	}
+}
+
+func TestTinyLFUCache_Delete(t *testing.T) {
+	c := NewTinyLFU[string, int](10)
+	c.Put("window", 1)
+	c.Put("main", 2)
+	c.Put("window", 1)
+
+	if !c.Delete("window") || !c.Delete("main") {
+		t.Error("Delete should return true for stored keys")
+	}
+	if c.Delete("missing") {
+		t.Error("Delete should return false for missing key")
+	}
+	if c.Len() != 0 {
+		t.Errorf("Len is %d, expected 0", c.Len())
+	}
+}
+
+func TestTinyLFUCache_Stats(t *testing.T) {
+	c := NewTinyLFU[string, int](10)
+	c.Put("a", 1)
+	c.Get("a")
+	c.Get("missing")
+
+	stats := c.Stats()
+	if stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("stats = %+v, expected 1 hit and 1 miss", stats)
+	}
+}
@@ -0,0 +1,81 @@
+package cache
+
+// TwoQueuePolicy is the full 2Q (Johnson & Shasha, VLDB'94).
+//
+//   - A1in: FIFO for keys seen once recently, about 25% of capacity. Hits here don't promote anything,
+//     because correlated re-references right after the first access say nothing about popularity.
+//   - A1out: ghost FIFO (keys only, no values) of keys recently pushed out of A1in, about 50% of capacity.
+//   - Am: LRU of keys that came back while their ghost was still in A1out - these are the really hot ones.
+//
+// A scan only passes through A1in and A1out and never touches Am.
+type TwoQueuePolicy[K comparable] struct {
+	capacity int
+	kin      int
+	kout     int
+	a1in     *keyList[K]
+	a1out    *keyList[K]
+	am       *keyList[K]
+}
+
+// Time: O(1)
+// Space: O(capacity) when full, plus capacity/2 ghost keys
+func New2QPolicy[K comparable](capacity int) *TwoQueuePolicy[K] {
+	return &TwoQueuePolicy[K]{
+		capacity: capacity,
+		kin:      max(1, capacity/4),
+		kout:     max(1, capacity/2),
+		a1in:     newKeyList[K](),
+		a1out:    newKeyList[K](),
+		am:       newKeyList[K](),
+	}
+}
+
+// Time: O(1)
+// Space: O(1)
+func (p *TwoQueuePolicy[K]) Hit(key K) {
+	p.am.MoveToFront(key)
+}
+
+// Time: O(1)
+// Space: O(1)
+func (p *TwoQueuePolicy[K]) Insert(key K) (K, bool) {
+	if p.capacity <= 0 {
+		return key, true
+	}
+
+	victim, evicted := p.reclaim()
+	if p.a1out.Remove(key) {
+		p.am.PushFront(key)
+	} else {
+		p.a1in.PushFront(key)
+	}
+	return victim, evicted
+}
+
+// Time: O(1)
+// Space: O(1)
+func (p *TwoQueuePolicy[K]) reclaim() (K, bool) {
+	if p.a1in.Len()+p.am.Len() < p.capacity {
+		var zero K
+		return zero, false
+	}
+
+	if p.a1in.Len() > p.kin || p.am.Len() == 0 {
+		victim, _ := p.a1in.PopBack()
+		p.a1out.PushFront(victim)
+		if p.a1out.Len() > p.kout {
+			p.a1out.PopBack()
+		}
+		return victim, true
+	}
+
+	return p.am.PopBack()
+}
+
+// Time: O(1)
+// Space: O(1)
+func (p *TwoQueuePolicy[K]) Remove(key K) {
+	if !p.a1in.Remove(key) {
+		p.am.Remove(key)
+	}
+}
@@ -0,0 +1,66 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestTwoQueuePolicy_GhostHitPromotesToAm(t *testing.T) {
+	p := New2QPolicy[string](4)
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		p.Insert(key)
+	}
+
+	if !p.a1out.Contains("a") {
+		t.Fatal("a should be remembered in A1out ghost list after eviction")
+	}
+
+	p.Insert("a")
+	if !p.am.Contains("a") {
+		t.Error("a came back while in A1out, it should go to Am")
+	}
+}
+
+func TestTwoQueuePolicy_ScanDoesNotFlushAm(t *testing.T) {
+	c := NewPolicyCache[string, int](New2QPolicy[string](8))
+
+	hot := []string{"h1", "h2", "h3"}
+	for _, key := range hot {
+		c.Put(key, 0)
+	}
+	for i := range 8 {
+		c.Put(fmt.Sprint("warmup_", i), i)
+	}
+	for _, key := range hot {
+		c.Put(key, 1) // back from ghost list, goes to Am
+	}
+
+	for i := range 100 {
+		c.Put(fmt.Sprint("scan_", i), i)
+	}
+
+	for _, key := range hot {
+		if _, ok := c.Get(key); !ok {
+			t.Errorf("%s should survive the scan in Am", key)
+		}
+	}
+}
+
+func TestTwoQueuePolicy_HitInA1inDoesNotPromote(t *testing.T) {
+	p := New2QPolicy[string](4)
+	p.Insert("a")
+	p.Hit("a")
+
+	if !p.a1in.Contains("a") || p.am.Contains("a") {
+		t.Error("hit in A1in should not promote key to Am")
+	}
+}
+
+func TestTwoQueuePolicy_CapacityOne(t *testing.T) {
+	p := New2QPolicy[string](1)
+	p.Insert("a")
+	victim, evicted := p.Insert("b")
+	if !evicted || victim != "a" {
+		t.Errorf("Insert returned (%q, %v), expected (a, true)", victim, evicted)
+	}
+}
@@ -0,0 +1,223 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// WriteBackCache is an LFUCache in front of a Store where Put only marks the entry dirty
+// instead of writing it through. Dirty entries reach the Store when:
+//   - they are about to be evicted: the victim is peeked from the minFreq bucket and saved
+//     *before* the new entry pushes it out; if Save fails, Put fails and nothing is dropped;
+//   - Flush is called explicitly (or by the WithFlushInterval timer, or by Close).
+//
+// Expired dirty entries (when WithDefaultTTL is used) are saved from the OnEvict hook as they leave;
+// a failed save there can't stop the expiry, so its error is kept and returned by the next Flush.
+//
+// Many Puts to the same hot key turn into a single Save - that's the whole point of write-back.
+// The price: until flushed, the latest values live only in memory.
+//
+// All methods are safe for concurrent use; Store calls are made while holding the cache lock.
+type WriteBackCache[K comparable, V any] struct {
+	mu       sync.Mutex
+	cache    *LFUCache[K, V]
+	store    Store[K, V]
+	dirty    map[K]struct{}
+	evictErr error
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// WithFlushInterval makes WriteBackCache flush dirty entries in background every interval.
+func WithFlushInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.flushInterval = interval
+	}
+}
+
+// Time: O(1)
+// Space: O(1) initialization, O(capacity) when full
+func NewWriteBackCache[K comparable, V any](capacity int, store Store[K, V], opts ...Option) *WriteBackCache[K, V] {
+	c := &WriteBackCache[K, V]{
+		cache: NewLFU[K, V](capacity, opts...),
+		store: store,
+		dirty: make(map[K]struct{}),
+	}
+	c.cache.OnEvict(c.onEvict)
+
+	if interval := newOptions(opts).flushInterval; interval > 0 {
+		c.stop = make(chan struct{})
+		c.done = make(chan struct{})
+		go c.flushLoop(interval)
+	}
+	return c
+}
+
+// Get returns the cached value or loads it from the Store (the loaded entry is clean).
+// Time: O(1) + Store.Load on miss (+ Store.Save if a dirty victim has to go)
+// Space: O(1)
+func (c *WriteBackCache[K, V]) Get(key K) (V, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if value, ok := c.cache.Get(key); ok {
+		return value, nil
+	}
+
+	value, err := c.store.Load(key)
+	if err != nil {
+		return value, err
+	}
+	if err := c.saveVictim(key); err != nil {
+		return value, err
+	}
+	c.cache.Put(key, value)
+	return value, nil
+}
+
+// Put stores the value in cache only and marks it dirty.
+// A value the cache can't hold (capacity <= 0) is saved to the Store right away.
+// Time: O(1) (+ Store.Save if a dirty victim has to go or the value is written through)
+// Space: O(1)
+func (c *WriteBackCache[K, V]) Put(key K, value V) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.saveVictim(key); err != nil {
+		return err
+	}
+	c.cache.Put(key, value)
+	if _, ok := c.cache.cache[key]; !ok {
+		// the cache did not take it (capacity <= 0): write through, a write must never be lost
+		return c.store.Save(key, value)
+	}
+	c.dirty[key] = struct{}{}
+	return nil
+}
+
+// Delete removes the key from both the cache and the Store.
+// Time: O(1) + Store.Delete
+// Space: O(1)
+func (c *WriteBackCache[K, V]) Delete(key K) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.cache.Delete(key)
+	delete(c.dirty, key)
+	return c.store.Delete(key)
+}
+
+// Flush saves every dirty entry. Entries that failed to save stay dirty and will be retried next time.
+// Time: O(d) Store.Save calls, d = number of dirty entries
+// Space: O(1)
+func (c *WriteBackCache[K, V]) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.flush()
+}
+
+func (c *WriteBackCache[K, V]) flush() error {
+	errs := []error{c.evictErr}
+	c.evictErr = nil
+
+	for key := range c.dirty {
+		elem, ok := c.cache.cache[key]
+		if !ok {
+			delete(c.dirty, key)
+			continue
+		}
+		if err := c.store.Save(key, elem.Value.(*cacheEntry[K, V]).value); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		delete(c.dirty, key)
+	}
+	return errors.Join(errs...)
+}
+
+// Close stops the background flusher (if any) and flushes what is left.
+// Time: O(d) Store.Save calls
+// Space: O(1)
+func (c *WriteBackCache[K, V]) Close() error {
+	if c.stop != nil {
+		close(c.stop)
+		<-c.done
+		c.stop = nil
+	}
+	return c.Flush()
+}
+
+// Time: O(1)
+// Space: O(1)
+func (c *WriteBackCache[K, V]) IsDirty(key K) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.dirty[key]
+	return ok
+}
+
+// Time: O(1)
+// Space: O(1)
+func (c *WriteBackCache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cache.Len()
+}
+
+// saveVictim persists the entry LFUCache is going to evict for a new key, if that entry is dirty.
+// Updating a stored key never evicts anything, so there is nothing to do then.
+// Time: O(1) + Store.Save
+// Space: O(1)
+func (c *WriteBackCache[K, V]) saveVictim(key K) error {
+	if _, ok := c.cache.cache[key]; ok || c.cache.Len() < c.cache.capacity {
+		return nil
+	}
+
+	victim, ok := c.cache.victim()
+	if !ok {
+		return nil
+	}
+	if _, dirty := c.dirty[victim.key]; !dirty {
+		return nil
+	}
+	if err := c.store.Save(victim.key, victim.value); err != nil {
+		return err
+	}
+	delete(c.dirty, victim.key)
+	return nil
+}
+
+// onEvict is the safety net for entries leaving without saveVictim: expiry mostly.
+// Called by LFUCache with c.mu already held.
+func (c *WriteBackCache[K, V]) onEvict(key K, value V, reason EvictReason) {
+	if reason == EvictReplaced || reason == EvictDeleted {
+		return
+	}
+	if _, dirty := c.dirty[key]; !dirty {
+		return
+	}
+
+	delete(c.dirty, key)
+	if err := c.store.Save(key, value); err != nil {
+		c.evictErr = errors.Join(c.evictErr, err)
+	}
+}
+
+func (c *WriteBackCache[K, V]) flushLoop(interval time.Duration) {
+	defer close(c.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			// failed entries stay dirty, the next tick retries them
+			_ = c.Flush()
+		case <-c.stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package cache
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// failingStore wraps MemoryStore and fails Save while failSaves is set.
+type failingStore struct {
+	*MemoryStore[string, int]
+	failSaves bool
+}
+
+func (s *failingStore) Save(key string, value int) error {
+	if s.failSaves {
+		return errBackendDown
+	}
+	return s.MemoryStore.Save(key, value)
+}
+
+func TestWriteBackCache_PutIsDeferredUntilFlush(t *testing.T) {
+	store := NewMemoryStore[string, int]()
+	c := NewWriteBackCache[string, int](10, store)
+
+	for i := range 5 {
+		if err := c.Put("hot", i); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+	}
+	if !c.IsDirty("hot") {
+		t.Error("hot should be dirty after Put")
+	}
+	if _, err := store.Load("hot"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("store has hot before flush, err = %v", err)
+	}
+
+	if err := c.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if val, err := store.Load("hot"); err != nil || val != 4 {
+		t.Errorf("store hot = %d, %v, expected 4, nil", val, err)
+	}
+	if store.Saves() != 1 {
+		t.Errorf("store saves = %d, expected 1 (writes coalesced)", store.Saves())
+	}
+	if c.IsDirty("hot") {
+		t.Error("hot should be clean after Flush")
+	}
+
+	if err := c.Flush(); err != nil || store.Saves() != 1 {
+		t.Errorf("second Flush saved again: saves = %d, err = %v", store.Saves(), err)
+	}
+}
+
+func TestWriteBackCache_ZeroCapacityWritesThrough(t *testing.T) {
+	store := NewMemoryStore[string, int]()
+	c := NewWriteBackCache[string, int](0, store)
+
+	if err := c.Put("k", 7); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if c.IsDirty("k") {
+		t.Error("k is not cached, it should not be dirty")
+	}
+	if got, err := store.Load("k"); err != nil || got != 7 {
+		t.Errorf("store has %d, %v, expected 7, nil", got, err)
+	}
+
+	failing := &failingStore{MemoryStore: NewMemoryStore[string, int](), failSaves: true}
+	c2 := NewWriteBackCache[string, int](0, failing)
+	if err := c2.Put("k", 1); !errors.Is(err, errBackendDown) {
+		t.Errorf("Put with failing store = %v, expected errBackendDown", err)
+	}
+}
+
+func TestWriteBackCache_DirtyVictimSavedBeforeEviction(t *testing.T) {
+	store := NewMemoryStore[string, int]()
+	c := NewWriteBackCache[string, int](2, store)
+
+	c.Put("a", 1)
+	c.Put("b", 2)
+	c.Get("b")
+	c.Put("c", 3) // evicts "a"
+
+	if val, err := store.Load("a"); err != nil || val != 1 {
+		t.Errorf("evicted a in store = %d, %v, expected 1, nil", val, err)
+	}
+	if _, err := store.Load("b"); err == nil {
+		t.Error("b is still cached, it should not be saved yet")
+	}
+	if c.Len() != 2 {
+		t.Errorf("Len = %d, expected 2", c.Len())
+	}
+}
+
+func TestWriteBackCache_FailedVictimSaveKeepsEntry(t *testing.T) {
+	store := &failingStore{MemoryStore: NewMemoryStore[string, int]()}
+	c := NewWriteBackCache[string, int](1, Store[string, int](store))
+
+	c.Put("a", 1)
+	store.failSaves = true
+
+	if err := c.Put("b", 2); !errors.Is(err, errBackendDown) {
+		t.Fatalf("Put err = %v, expected errBackendDown", err)
+	}
+	if val, err := c.Get("a"); err != nil || val != 1 {
+		t.Errorf("Get(a) = %d, %v, dirty a must survive failed save", val, err)
+	}
+	if !c.IsDirty("a") {
+		t.Error("a should stay dirty")
+	}
+
+	store.failSaves = false
+	if err := c.Put("b", 2); err != nil {
+		t.Fatalf("Put after recovery: %v", err)
+	}
+	if val, err := store.Load("a"); err != nil || val != 1 {
+		t.Errorf("store a = %d, %v, expected 1, nil", val, err)
+	}
+}
+
+func TestWriteBackCache_FailedFlushRetries(t *testing.T) {
+	store := &failingStore{MemoryStore: NewMemoryStore[string, int]()}
+	c := NewWriteBackCache[string, int](10, Store[string, int](store))
+
+	c.Put("a", 1)
+	store.failSaves = true
+	if err := c.Flush(); !errors.Is(err, errBackendDown) {
+		t.Errorf("Flush err = %v, expected errBackendDown", err)
+	}
+	if !c.IsDirty("a") {
+		t.Error("a should stay dirty after failed flush")
+	}
+
+	store.failSaves = false
+	if err := c.Flush(); err != nil {
+		t.Errorf("Flush: %v", err)
+	}
+	if val, err := store.Load("a"); err != nil || val != 1 {
+		t.Errorf("store a = %d, %v, expected 1, nil", val, err)
+	}
+}
+
+func TestWriteBackCache_GetLoadsCleanEntry(t *testing.T) {
+	store := NewMemoryStore[string, int]()
+	store.Save("a", 42)
+	c := NewWriteBackCache[string, int](10, store)
+
+	if val, err := c.Get("a"); err != nil || val != 42 {
+		t.Errorf("Get(a) = %d, %v, expected 42, nil", val, err)
+	}
+	if c.IsDirty("a") {
+		t.Error("loaded entry should be clean")
+	}
+	if _, err := c.Get("missing"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Get(missing) err = %v, expected ErrKeyNotFound", err)
+	}
+
+	c.Flush()
+	if store.Saves() != 1 {
+		t.Errorf("store saves = %d, expected 1 (clean entries are not written back)", store.Saves())
+	}
+}
+
+func TestWriteBackCache_Delete(t *testing.T) {
+	store := NewMemoryStore[string, int]()
+	store.Save("a", 1)
+	c := NewWriteBackCache[string, int](10, store)
+
+	c.Put("a", 2)
+	if err := c.Delete("a"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := c.Get("a"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Get after Delete err = %v, expected ErrKeyNotFound", err)
+	}
+	c.Flush()
+	if store.Len() != 0 {
+		t.Errorf("store Len = %d, expected 0", store.Len())
+	}
+}
+
+func TestWriteBackCache_ExpiredDirtyEntrySaved(t *testing.T) {
+	clock := newFakeClock()
+	store := NewMemoryStore[string, int]()
+	c := NewWriteBackCache[string, int](10, store, WithClock(clock.Now), WithDefaultTTL(time.Minute))
+
+	c.Put("a", 1)
+	clock.Advance(2 * time.Minute)
+
+	if val, err := c.Get("a"); err != nil || val != 1 {
+		t.Errorf("Get(a) = %d, %v, expected value reloaded from store", val, err)
+	}
+	if c.IsDirty("a") {
+		t.Error("reloaded entry should be clean")
+	}
+}
+
+func TestWriteBackCache_BackgroundFlush(t *testing.T) {
+	store := NewMemoryStore[string, int]()
+	c := NewWriteBackCache[string, int](10, store, WithFlushInterval(5*time.Millisecond))
+	defer c.Close()
+
+	c.Put("a", 1)
+
+	deadline := time.Now().Add(time.Second)
+	for store.Len() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if val, err := store.Load("a"); err != nil || val != 1 {
+		t.Errorf("store a = %d, %v, expected background flush", val, err)
+	}
+}
+
+func TestWriteBackCache_CloseFlushes(t *testing.T) {
+	store := NewMemoryStore[string, int]()
+	c := NewWriteBackCache[string, int](10, store, WithFlushInterval(time.Hour))
+
+	c.Put("a", 1)
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if val, err := store.Load("a"); err != nil || val != 1 {
+		t.Errorf("store a = %d, %v, expected 1, nil", val, err)
+	}
+}
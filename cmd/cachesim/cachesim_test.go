@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReadTrace_Plain(t *testing.T) {
+	trace := "# comment\na\nb 100\n\na\n"
+	reqs, err := ReadTrace(strings.NewReader(trace), FormatPlain)
+	if err != nil {
+		t.Fatalf("ReadTrace: %v", err)
+	}
+
+	expected := []Request{{"a", 1}, {"b", 100}, {"a", 1}}
+	if len(reqs) != len(expected) {
+		t.Fatalf("got %d requests, expected %d", len(reqs), len(expected))
+	}
+	for i := range expected {
+		if reqs[i] != expected[i] {
+			t.Errorf("request %d = %+v, expected %+v", i, reqs[i], expected[i])
+		}
+	}
+}
+
+func TestReadTrace_ARCExpandsBlocks(t *testing.T) {
+	reqs, err := ReadTrace(strings.NewReader("10 3 0 1\n5 1 0 2\n"), FormatARC)
+	if err != nil {
+		t.Fatalf("ReadTrace: %v", err)
+	}
+
+	keys := make([]string, len(reqs))
+	for i, r := range reqs {
+		keys[i] = r.Key
+		if r.Size != arcBlockSize {
+			t.Errorf("request %d size = %d, expected %d", i, r.Size, arcBlockSize)
+		}
+	}
+	if got := strings.Join(keys, ","); got != "10,11,12,5" {
+		t.Errorf("keys = %s, expected 10,11,12,5", got)
+	}
+}
+
+func TestReadTrace_LIRSSkipsMarkers(t *testing.T) {
+	reqs, err := ReadTrace(strings.NewReader("1\n2\n*\n1\n"), FormatLIRS)
+	if err != nil {
+		t.Fatalf("ReadTrace: %v", err)
+	}
+	if len(reqs) != 3 {
+		t.Errorf("got %d requests, expected 3", len(reqs))
+	}
+}
+
+func TestReadTrace_Errors(t *testing.T) {
+	if _, err := ReadTrace(strings.NewReader("a\n"), "bogus"); !errors.Is(err, ErrUnknownTraceFormat) {
+		t.Errorf("err = %v, expected ErrUnknownTraceFormat", err)
+	}
+	if _, err := ReadTrace(strings.NewReader("a\nb -1\n"), FormatPlain); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("err = %v, expected error on line 2", err)
+	}
+	if _, err := ReadTrace(strings.NewReader("x 1 0 0\n"), FormatARC); err == nil {
+		t.Error("expected error for non-numeric ARC block")
+	}
+	for _, line := range []string{"0 9223372036854775807 0 1", "9223372036854775800 100 0 1", "-5 1 0 1"} {
+		if _, err := ReadTrace(strings.NewReader("1 1 0 0\n"+line+"\n"), FormatARC); err == nil || !strings.Contains(err.Error(), "line 2") {
+			t.Errorf("%q: err = %v, expected error on line 2", line, err)
+		}
+	}
+}
+
+func TestReplay_CountsHitsBytesAndEvictions(t *testing.T) {
+	c, err := newSimCache("lru", 2)
+	if err != nil {
+		t.Fatalf("newSimCache: %v", err)
+	}
+	reqs := []Request{{"a", 10}, {"b", 1}, {"a", 10}, {"c", 1}, {"b", 1}}
+
+	res := Replay(c, reqs)
+
+	// a miss, b miss, a hit, c miss (evicts b), b miss (evicts a)
+	if res.Requests != 5 || res.Hits != 1 || res.Evictions != 2 {
+		t.Errorf("result = %+v, expected 5 requests, 1 hit, 2 evictions", res)
+	}
+	if res.HitRatio() != 0.2 {
+		t.Errorf("HitRatio = %v, expected 0.2", res.HitRatio())
+	}
+	if res.ByteHitRatio() != 10.0/23.0 {
+		t.Errorf("ByteHitRatio = %v, expected %v", res.ByteHitRatio(), 10.0/23.0)
+	}
+}
+
+func TestSweep_AllCaches(t *testing.T) {
+	var reqs []Request
+	for i := range 2000 {
+		// small hot set plus a scan of cold keys
+		reqs = append(reqs, Request{Key: string(rune('a' + i%5)), Size: 1})
+		reqs = append(reqs, Request{Key: strings.Repeat("x", 1+i%300), Size: 1})
+	}
+
+	names := []string{"lru", "lfu", "arc", "2q", "sieve", CacheLFUCache, CacheTinyLFU, CacheNative}
+	results, err := Sweep(reqs, names, []int{10, 50})
+	if err != nil {
+		t.Fatalf("Sweep: %v", err)
+	}
+	if len(results) != len(names)*2 {
+		t.Fatalf("got %d results, expected %d", len(results), len(names)*2)
+	}
+	for _, r := range results {
+		if r.Requests != len(reqs) {
+			t.Errorf("%s/%d: requests = %d, expected %d", r.Cache, r.Capacity, r.Requests, len(reqs))
+		}
+		if r.HitRatio() <= 0 || r.HitRatio() > 1 {
+			t.Errorf("%s/%d: hit ratio %v out of (0, 1]", r.Cache, r.Capacity, r.HitRatio())
+		}
+		if r.Evictions < 0 {
+			t.Errorf("%s/%d: negative evictions %d", r.Cache, r.Capacity, r.Evictions)
+		}
+	}
+
+	if _, err := Sweep(reqs, []string{"bogus"}, []int{10}); err == nil {
+		t.Error("expected error for unknown cache")
+	}
+}
+
+func TestWriteTableAndCSV(t *testing.T) {
+	results := []Result{{Cache: "lru", Capacity: 10, Requests: 4, Hits: 1, Bytes: 4, HitBytes: 1, Evictions: 2}}
+
+	var table bytes.Buffer
+	if err := WriteTable(&table, results); err != nil {
+		t.Fatalf("WriteTable: %v", err)
+	}
+	if !strings.Contains(table.String(), "hit_ratio") || !strings.Contains(table.String(), "0.2500") {
+		t.Errorf("unexpected table:\n%s", table.String())
+	}
+
+	var out bytes.Buffer
+	if err := WriteCSV(&out, results); err != nil {
+		t.Fatalf("WriteCSV: %v", err)
+	}
+	expected := "cache,capacity,requests,hits,hit_ratio,byte_hit_ratio,evictions\nlru,10,4,1,0.2500,0.2500,2\n"
+	if out.String() != expected {
+		t.Errorf("CSV = %q, expected %q", out.String(), expected)
+	}
+}
+
+func TestParseCapacities(t *testing.T) {
+	caps, err := parseCapacities("10, 100,1000")
+	if err != nil || len(caps) != 3 || caps[2] != 1000 {
+		t.Errorf("parseCapacities = %v, %v", caps, err)
+	}
+	if _, err := parseCapacities("10,0"); err == nil {
+		t.Error("expected error for zero capacity")
+	}
+}
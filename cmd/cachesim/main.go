@@ -0,0 +1,78 @@
+// Command cachesim replays an access trace against caches from the cache package
+// and reports hit ratio, byte hit ratio and evictions for every cache and capacity.
+//
+//	cachesim -trace P1.lis -format arc -caches lru,arc,sieve,tinylfu -capacities 1000,10000,100000
+//	cachesim -trace keys.txt -output csv > results.csv
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func main() {
+	tracePath := flag.String("trace", "", "trace file, \"-\" for stdin")
+	format := flag.String("format", FormatPlain, "trace format: plain, arc or lirs")
+	caches := flag.String("caches", "lru,lfu,arc,2q,sieve,lfucache,tinylfu", "comma-separated caches: lru, lfu, arc, 2q, sieve, lfucache, tinylfu, native")
+	capacities := flag.String("capacities", "100,1000,10000", "comma-separated capacities (in entries)")
+	output := flag.String("output", "table", "output: table or csv")
+	flag.Parse()
+
+	if err := run(*tracePath, *format, *caches, *capacities, *output); err != nil {
+		fmt.Fprintln(os.Stderr, "cachesim:", err)
+		os.Exit(1)
+	}
+}
+
+func run(tracePath, format, caches, capacities, output string) error {
+	if tracePath == "" {
+		return fmt.Errorf("-trace is required")
+	}
+	caps, err := parseCapacities(capacities)
+	if err != nil {
+		return err
+	}
+
+	in := os.Stdin
+	if tracePath != "-" {
+		f, err := os.Open(tracePath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	requests, err := ReadTrace(in, format)
+	if err != nil {
+		return err
+	}
+	results, err := Sweep(requests, strings.Split(caches, ","), caps)
+	if err != nil {
+		return err
+	}
+
+	switch output {
+	case "table":
+		return WriteTable(os.Stdout, results)
+	case "csv":
+		return WriteCSV(os.Stdout, results)
+	default:
+		return fmt.Errorf("unknown output %q", output)
+	}
+}
+
+func parseCapacities(s string) ([]int, error) {
+	var caps []int
+	for _, field := range strings.Split(s, ",") {
+		capacity, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || capacity <= 0 {
+			return nil, fmt.Errorf("invalid capacity %q", field)
+		}
+		caps = append(caps, capacity)
+	}
+	return caps, nil
+}
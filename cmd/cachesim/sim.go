@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/m0n0x41d/algopher/cache"
+)
+
+const (
+	CacheLFUCache = "lfucache"
+	CacheTinyLFU  = "tinylfu"
+	CacheNative   = "native"
+)
+
+// simCache is what replay needs from a cache. Values are request sizes.
+type simCache interface {
+	Get(key string) bool
+	Put(key string, size int64)
+	Len() int
+}
+
+type cacheAdapter struct {
+	c cache.Cache[string, int64]
+}
+
+func (a cacheAdapter) Get(key string) bool {
+	_, ok := a.c.Get(key)
+	return ok
+}
+
+func (a cacheAdapter) Put(key string, size int64) { a.c.Put(key, size) }
+func (a cacheAdapter) Len() int                   { return a.c.Len() }
+
+// NativeCache returns an error on miss instead of a bool.
+type nativeAdapter struct {
+	c *cache.NativeCache[int64]
+}
+
+func (a nativeAdapter) Get(key string) bool {
+	_, err := a.c.Get(key)
+	return err == nil
+}
+
+func (a nativeAdapter) Put(key string, size int64) { a.c.Put(key, size) }
+func (a nativeAdapter) Len() int                   { return a.c.Len() }
+
+// newSimCache builds a cache by name: any policy known to cache.NewPolicy,
+// plus "lfucache" (O(1) LFUCache), "tinylfu" and "native" (NativeCache with probing step 1).
+// Time: O(1), O(capacity) for native
+// Space: O(1) initialization, O(capacity) for native
+func newSimCache(name string, capacity int) (simCache, error) {
+	switch name {
+	case CacheLFUCache:
+		return cacheAdapter{cache.NewLFU[string, int64](capacity)}, nil
+	case CacheTinyLFU:
+		return cacheAdapter{cache.NewTinyLFU[string, int64](capacity)}, nil
+	case CacheNative:
+		nc, err := cache.NewNativeCache[int64](capacity, 1)
+		if err != nil {
+			return nil, err
+		}
+		return nativeAdapter{nc}, nil
+	default:
+		c, err := cache.NewCache[string, int64](name, capacity)
+		if err != nil {
+			return nil, err
+		}
+		return cacheAdapter{c}, nil
+	}
+}
+
+// Result of one trace replay.
+type Result struct {
+	Cache     string
+	Capacity  int
+	Requests  int
+	Hits      int
+	Bytes     int64
+	HitBytes  int64
+	Evictions int
+}
+
+// Time: O(1)
+// Space: O(1)
+func (r Result) HitRatio() float64 {
+	if r.Requests == 0 {
+		return 0
+	}
+	return float64(r.Hits) / float64(r.Requests)
+}
+
+// Time: O(1)
+// Space: O(1)
+func (r Result) ByteHitRatio() float64 {
+	if r.Bytes == 0 {
+		return 0
+	}
+	return float64(r.HitBytes) / float64(r.Bytes)
+}
+
+// Replay runs requests through the cache as a demand-filled cache would: Get, and Put on miss.
+// Every miss inserts one entry and nothing is deleted, so whatever is not in the cache
+// at the end was evicted: evictions = misses - final Len.
+// That works for every cache uniformly, including TinyLFU rejecting a candidate at the door.
+// Time: O(r) cache operations, r = len(requests)
+// Space: O(capacity)
+func Replay(c simCache, requests []Request) Result {
+	var res Result
+	misses := 0
+	for _, req := range requests {
+		res.Requests++
+		res.Bytes += req.Size
+		if c.Get(req.Key) {
+			res.Hits++
+			res.HitBytes += req.Size
+			continue
+		}
+		misses++
+		c.Put(req.Key, req.Size)
+	}
+	res.Evictions = misses - c.Len()
+	return res
+}
+
+// Sweep replays the trace against every cache at every capacity.
+// Time: O(len(caches) * len(capacities) * r)
+// Space: O(max capacity + results)
+func Sweep(requests []Request, caches []string, capacities []int) ([]Result, error) {
+	results := make([]Result, 0, len(caches)*len(capacities))
+	for _, name := range caches {
+		for _, capacity := range capacities {
+			c, err := newSimCache(name, capacity)
+			if err != nil {
+				return nil, err
+			}
+			res := Replay(c, requests)
+			res.Cache = name
+			res.Capacity = capacity
+			results = append(results, res)
+		}
+	}
+	return results, nil
+}
+
+var resultHeader = []string{"cache", "capacity", "requests", "hits", "hit_ratio", "byte_hit_ratio", "evictions"}
+
+func resultRow(r Result) []string {
+	return []string{
+		r.Cache,
+		strconv.Itoa(r.Capacity),
+		strconv.Itoa(r.Requests),
+		strconv.Itoa(r.Hits),
+		strconv.FormatFloat(r.HitRatio(), 'f', 4, 64),
+		strconv.FormatFloat(r.ByteHitRatio(), 'f', 4, 64),
+		strconv.Itoa(r.Evictions),
+	}
+}
+
+// WriteTable prints results as an aligned, human-readable table.
+// Time: O(len(results))
+// Space: O(1)
+func WriteTable(w io.Writer, results []Result) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	writeRow := func(row []string) {
+		for _, cell := range row {
+			fmt.Fprint(tw, cell, "\t")
+		}
+		fmt.Fprintln(tw)
+	}
+
+	writeRow(resultHeader)
+	for _, r := range results {
+		writeRow(resultRow(r))
+	}
+	return tw.Flush()
+}
+
+// WriteCSV prints results as CSV with a header line, ready for plotting.
+// Time: O(len(results))
+// Space: O(1)
+func WriteCSV(w io.Writer, results []Result) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(resultHeader); err != nil {
+		return err
+	}
+	for _, r := range results {
+		if err := cw.Write(resultRow(r)); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var ErrUnknownTraceFormat = errors.New("unknown trace format")
+
+const (
+	FormatPlain = "plain"
+	FormatARC   = "arc"
+	FormatLIRS  = "lirs"
+)
+
+// Request is a single access from a trace. Size is in bytes, 1 when the trace does not tell.
+type Request struct {
+	Key  string
+	Size int64
+}
+
+// ReadTrace parses the whole trace into memory, so the same requests can be replayed for every cache.
+//
+// Formats:
+//   - plain: one key per line, optionally followed by its size in bytes: "key [size]";
+//   - arc: Megiddo & Modha traces, "start_block num_blocks ignored request_no" -
+//     every line expands into num_blocks requests for consecutive 512-byte blocks;
+//   - lirs: one block number per line, "*" lines mark the end of a trace part and are skipped.
+//
+// Empty lines and lines starting with "#" are skipped in every format.
+// Time: O(n) where n = trace length
+// Space: O(r) where r = number of requests
+func ReadTrace(r io.Reader, format string) ([]Request, error) {
+	var parse func(fields []string) ([]Request, error)
+	switch format {
+	case FormatPlain:
+		parse = parsePlain
+	case FormatARC:
+		parse = parseARC
+	case FormatLIRS:
+		parse = parseLIRS
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownTraceFormat, format)
+	}
+
+	var requests []Request
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		reqs, err := parse(strings.Fields(line))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		requests = append(requests, reqs...)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+func parsePlain(fields []string) ([]Request, error) {
+	req := Request{Key: fields[0], Size: 1}
+	if len(fields) > 1 {
+		size, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil || size <= 0 {
+			return nil, fmt.Errorf("invalid size %q", fields[1])
+		}
+		req.Size = size
+	}
+	return []Request{req}, nil
+}
+
+const (
+	arcBlockSize = 512
+	// arcMaxBlocks bounds a single line (512 MiB of blocks): real traces stay far below,
+	// and a garbage count must not make us allocate the whole address space.
+	arcMaxBlocks = 1 << 20
+)
+
+func parseARC(fields []string) ([]Request, error) {
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("expected \"start_block num_blocks ...\", got %q", strings.Join(fields, " "))
+	}
+	start, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start block %q", fields[0])
+	}
+	count, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil || count <= 0 || count > arcMaxBlocks {
+		return nil, fmt.Errorf("invalid block count %q, expected 1..%d", fields[1], arcMaxBlocks)
+	}
+	if start < 0 || start > math.MaxInt64-count {
+		return nil, fmt.Errorf("block range %s+%s is out of range", fields[0], fields[1])
+	}
+
+	reqs := make([]Request, 0, count)
+	for block := start; block < start+count; block++ {
+		reqs = append(reqs, Request{Key: strconv.FormatInt(block, 10), Size: arcBlockSize})
+	}
+	return reqs, nil
+}
+
+func parseLIRS(fields []string) ([]Request, error) {
+	if fields[0] == "*" {
+		return nil, nil
+	}
+	if _, err := strconv.ParseInt(fields[0], 10, 64); err != nil {
+		return nil, fmt.Errorf("invalid block number %q", fields[0])
+	}
+	return []Request{{Key: fields[0], Size: 1}}, nil
+}
@@ -2,4 +2,4 @@ module github.com/m0n0x41d/algopher
 
 go 1.25.3
 
-require golang.org/x/exp v0.0.0-20251219203646-944ab1f22d93 // indirect
+require golang.org/x/exp v0.0.0-20251219203646-944ab1f22d93
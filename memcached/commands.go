@@ -0,0 +1,368 @@
+package memcached
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxLineLength bounds a command line with its "\r\n" (not the data block), as memcached's 2048 bytes.
+// Without it a client that never sends a newline makes the server buffer forever.
+const MaxLineLength = 2048
+
+var errLineTooLong = errors.New("memcached: command line too long")
+
+// exptime values above this many seconds are absolute unix timestamps, as in memcached.
+const relativeExptimeLimit = 60 * 60 * 24 * 30
+
+const (
+	replyStored    = "STORED"
+	replyNotStored = "NOT_STORED"
+	replyDeleted   = "DELETED"
+	replyNotFound  = "NOT_FOUND"
+	replyTouched   = "TOUCHED"
+	replyEnd       = "END"
+	replyError     = "ERROR"
+
+	clientErrorFormat  = "CLIENT_ERROR bad command line format"
+	clientErrorChunk   = "CLIENT_ERROR bad data chunk"
+	clientErrorLine    = "CLIENT_ERROR line too long"
+	clientErrorNumeric = "CLIENT_ERROR cannot increment or decrement non-numeric value"
+	clientErrorDelta   = "CLIENT_ERROR invalid numeric delta argument"
+	serverErrorTooBig  = "SERVER_ERROR object too large for cache"
+)
+
+// serveCommand reads and executes a single command.
+// A returned error means the connection is broken (or the client sent garbage we can't resync from).
+func (s *Server) serveCommand(r *bufio.Reader, w *bufio.Writer) (quit bool, err error) {
+	line, err := readLine(r)
+	if errors.Is(err, errLineTooLong) {
+		return false, replyFatal(w, clientErrorLine)
+	}
+	if err != nil {
+		return false, err
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return false, writeLine(w, replyError)
+	}
+
+	args := fields[1:]
+	switch fields[0] {
+	case "get":
+		return false, s.cmdGet(w, args, false)
+	case "gets":
+		return false, s.cmdGet(w, args, true)
+	case "set", "add":
+		return false, s.cmdStore(r, w, fields[0], args)
+	case "delete":
+		return false, s.cmdDelete(w, args)
+	case "incr":
+		return false, s.cmdIncr(w, args)
+	case "touch":
+		return false, s.cmdTouch(w, args)
+	case "stats":
+		return false, s.cmdStats(w, args)
+	case "quit":
+		return true, nil
+	default:
+		return false, writeLine(w, replyError)
+	}
+}
+
+// get <key>*
+// gets <key>*
+func (s *Server) cmdGet(w *bufio.Writer, keys []string, withCAS bool) error {
+	if len(keys) == 0 {
+		return writeLine(w, replyError)
+	}
+
+	for _, key := range keys {
+		s.mu.Lock()
+		it, ok := s.cache.Get(key)
+		s.stats.cmdGet++
+		if ok {
+			s.stats.getHits++
+		} else {
+			s.stats.getMisses++
+		}
+		s.mu.Unlock()
+
+		if !ok {
+			continue
+		}
+		if withCAS {
+			fmt.Fprintf(w, "VALUE %s %d %d %d\r\n", key, it.flags, len(it.data), it.cas)
+		} else {
+			fmt.Fprintf(w, "VALUE %s %d %d\r\n", key, it.flags, len(it.data))
+		}
+		w.Write(it.data)
+		w.WriteString("\r\n")
+	}
+	return writeLine(w, replyEnd)
+}
+
+// set <key> <flags> <exptime> <bytes> [noreply]\r\n<data>\r\n
+// add <key> <flags> <exptime> <bytes> [noreply]\r\n<data>\r\n
+func (s *Server) cmdStore(r *bufio.Reader, w *bufio.Writer, cmd string, args []string) error {
+	args, noreply := cutNoreply(args)
+	if len(args) != 4 || !validKey(args[0]) {
+		return writeLine(w, clientErrorFormat)
+	}
+	flags, err1 := strconv.ParseUint(args[1], 10, 32)
+	exptime, err2 := strconv.ParseInt(args[2], 10, 64)
+	size, err3 := strconv.Atoi(args[3])
+	if err1 != nil || err2 != nil || err3 != nil || size < 0 {
+		return writeLine(w, clientErrorFormat)
+	}
+
+	if size > MaxValueSize {
+		// swallow the data block to stay in sync with the client
+		if _, err := io.CopyN(io.Discard, r, int64(size)+2); err != nil {
+			return err
+		}
+		return writeLine(w, serverErrorTooBig)
+	}
+
+	data := make([]byte, size+2)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return err
+	}
+	if !bytes.HasSuffix(data, []byte("\r\n")) {
+		return replyFatal(w, clientErrorChunk)
+	}
+	data = data[:size]
+
+	key := args[0]
+	s.mu.Lock()
+	s.stats.cmdSet++
+	result := replyStored
+	if cmd == "add" && s.exists(key) {
+		result = replyNotStored
+	} else {
+		s.store(key, item{flags: uint32(flags), data: data}, s.expiresAt(exptime))
+	}
+	s.mu.Unlock()
+
+	return reply(w, result, noreply)
+}
+
+// delete <key> [noreply]
+func (s *Server) cmdDelete(w *bufio.Writer, args []string) error {
+	args, noreply := cutNoreply(args)
+	if len(args) != 1 {
+		return writeLine(w, clientErrorFormat)
+	}
+
+	s.mu.Lock()
+	deleted := s.cache.Delete(args[0])
+	s.mu.Unlock()
+
+	if deleted {
+		return reply(w, replyDeleted, noreply)
+	}
+	return reply(w, replyNotFound, noreply)
+}
+
+// incr <key> <value> [noreply]
+// The stored value must be a decimal uint64, the result wraps around on overflow like in memcached.
+func (s *Server) cmdIncr(w *bufio.Writer, args []string) error {
+	args, noreply := cutNoreply(args)
+	if len(args) != 2 {
+		return writeLine(w, clientErrorFormat)
+	}
+	delta, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil {
+		return writeLine(w, clientErrorDelta)
+	}
+
+	s.mu.Lock()
+	it, ok := s.cache.Get(args[0])
+	if !ok {
+		s.mu.Unlock()
+		return reply(w, replyNotFound, noreply)
+	}
+	current, err := strconv.ParseUint(string(it.data), 10, 64)
+	if err != nil {
+		s.mu.Unlock()
+		return writeLine(w, clientErrorNumeric)
+	}
+	value := strconv.FormatUint(current+delta, 10)
+	s.store(args[0], item{flags: it.flags, data: []byte(value)}, it.expiresAt)
+	s.mu.Unlock()
+
+	return reply(w, value, noreply)
+}
+
+// touch <key> <exptime> [noreply]
+func (s *Server) cmdTouch(w *bufio.Writer, args []string) error {
+	args, noreply := cutNoreply(args)
+	if len(args) != 2 {
+		return writeLine(w, clientErrorFormat)
+	}
+	exptime, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return writeLine(w, clientErrorFormat)
+	}
+
+	s.mu.Lock()
+	s.stats.cmdTouch++
+	it, ok := s.cache.Get(args[0])
+	if ok {
+		// touch changes only the expiration, it keeps the cas value
+		s.store(args[0], it, s.expiresAt(exptime))
+	}
+	s.mu.Unlock()
+
+	if ok {
+		return reply(w, replyTouched, noreply)
+	}
+	return reply(w, replyNotFound, noreply)
+}
+
+// stats
+func (s *Server) cmdStats(w *bufio.Writer, args []string) error {
+	if len(args) != 0 {
+		// stats subcommands (items, slabs, ...) are not supported
+		return writeLine(w, replyError)
+	}
+
+	s.mu.Lock()
+	now := s.clock()
+	cs := s.cache.Stats()
+	stats := []struct {
+		name  string
+		value any
+	}{
+		{"uptime", int64(now.Sub(s.stats.started).Seconds())},
+		{"time", now.Unix()},
+		{"curr_connections", s.stats.currConnections},
+		{"total_connections", s.stats.totalConnections},
+		{"cmd_get", s.stats.cmdGet},
+		{"cmd_set", s.stats.cmdSet},
+		{"cmd_touch", s.stats.cmdTouch},
+		{"get_hits", s.stats.getHits},
+		{"get_misses", s.stats.getMisses},
+		{"curr_items", s.cache.Len()},
+		{"evictions", cs.Evictions},
+	}
+	s.mu.Unlock()
+
+	for _, st := range stats {
+		fmt.Fprintf(w, "STAT %s %v\r\n", st.name, st.value)
+	}
+	return writeLine(w, replyEnd)
+}
+
+// store puts the item, a new item (zero cas) gets the next cas value. Already expired items are just removed.
+// Must be called with s.mu held.
+func (s *Server) store(key string, it item, expiresAt time.Time) {
+	ttl := s.ttl(expiresAt)
+	if ttl < 0 {
+		s.cache.Delete(key)
+		return
+	}
+	if it.cas == 0 {
+		s.cas++
+		it.cas = s.cas
+	}
+	it.expiresAt = expiresAt
+	s.cache.PutWithTTL(key, it, ttl)
+}
+
+// exists must be called with s.mu held.
+func (s *Server) exists(key string) bool {
+	_, ok := s.cache.Get(key)
+	return ok
+}
+
+// ttl converts an absolute expiration to LFUCache TTL: 0 means "never", negative means "already expired".
+func (s *Server) ttl(expiresAt time.Time) time.Duration {
+	if expiresAt.IsZero() {
+		return 0
+	}
+	ttl := expiresAt.Sub(s.clock())
+	if ttl <= 0 {
+		return -1
+	}
+	return ttl
+}
+
+// expiresAt interprets memcached exptime: 0 - never expires, negative - already expired,
+// up to 30 days - seconds from now, anything bigger - unix timestamp.
+func (s *Server) expiresAt(exptime int64) time.Time {
+	now := s.clock()
+	switch {
+	case exptime == 0:
+		return time.Time{}
+	case exptime < 0:
+		return now.Add(-time.Second)
+	case exptime <= relativeExptimeLimit:
+		return now.Add(time.Duration(exptime) * time.Second)
+	default:
+		return time.Unix(exptime, 0)
+	}
+}
+
+func validKey(key string) bool {
+	if len(key) == 0 || len(key) > MaxKeyLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] <= ' ' || key[i] == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+func cutNoreply(args []string) ([]string, bool) {
+	if n := len(args); n > 0 && args[n-1] == "noreply" {
+		return args[:n-1], true
+	}
+	return args, false
+}
+
+func reply(w *bufio.Writer, reply string, noreply bool) error {
+	if noreply {
+		return nil
+	}
+	return writeLine(w, reply)
+}
+
+// replyFatal sends msg before the connection is dropped: handleConn does not flush on errors.
+func replyFatal(w *bufio.Writer, msg string) error {
+	if err := writeLine(w, msg); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return fmt.Errorf("memcached: %s", msg)
+}
+
+// readLine reads up to '\n'. r must be MaxLineLength bytes large (see handleConn):
+// a full buffer without a newline means the line is too long.
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		return "", errLineTooLong
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(line), "\r\n"), nil
+}
+
+func writeLine(w *bufio.Writer, line string) error {
+	if _, err := w.WriteString(line); err != nil {
+		return err
+	}
+	_, err := w.WriteString("\r\n")
+	return err
+}
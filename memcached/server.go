@@ -0,0 +1,188 @@
+// Package memcached serves an LFUCache over TCP using a subset of the memcached text protocol:
+// get, gets, set, add, delete, incr, touch and stats. Any memcached client library can talk to it.
+package memcached
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/m0n0x41d/algopher/cache"
+)
+
+// Protocol limits, the same as memcached defaults.
+const (
+	MaxKeyLength = 250
+	MaxValueSize = 1 << 20
+)
+
+var ErrServerClosed = errors.New("memcached: server closed")
+
+// item is what is stored in cache for every key.
+// expiresAt is kept next to the value because incr has to re-put the item without changing its TTL,
+// and LFUCache does not expose expiration times.
+type item struct {
+	flags     uint32
+	data      []byte
+	cas       uint64
+	expiresAt time.Time
+}
+
+// Server is a memcached text-protocol front for a single LFUCache.
+// Connections are served concurrently, every command holds the server lock while touching the cache,
+// so commands are atomic with respect to each other (incr and add need that).
+type Server struct {
+	mu    sync.Mutex
+	cache *cache.LFUCache[string, item]
+	clock cache.Clock
+	cas   uint64
+	stats serverStats
+
+	connMu   sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+type serverStats struct {
+	started          time.Time
+	currConnections  int
+	totalConnections int
+	cmdGet           int
+	cmdSet           int
+	cmdTouch         int
+	getHits          int
+	getMisses        int
+}
+
+type Option func(*Server)
+
+// WithClock sets the time source used both for the cache TTLs and the memcached exptime arithmetic.
+func WithClock(clock cache.Clock) Option {
+	return func(s *Server) {
+		s.clock = clock
+	}
+}
+
+// Time: O(1)
+// Space: O(1) initialization, O(capacity) when full
+func NewServer(capacity int, opts ...Option) *Server {
+	s := &Server{
+		clock: time.Now,
+		conns: make(map[net.Conn]struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.cache = cache.NewLFU[string, item](capacity, cache.WithClock(s.clock))
+	s.stats.started = s.clock()
+	return s
+}
+
+// ListenAndServe listens on the TCP address and serves until Close.
+func (s *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts connections on l, one goroutine per connection.
+// It always returns a non-nil error, ErrServerClosed after Close.
+func (s *Server) Serve(l net.Listener) error {
+	s.connMu.Lock()
+	if s.closed {
+		s.connMu.Unlock()
+		l.Close()
+		return ErrServerClosed
+	}
+	s.listener = l
+	s.connMu.Unlock()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			s.connMu.Lock()
+			closed := s.closed
+			s.connMu.Unlock()
+			if closed {
+				return ErrServerClosed
+			}
+			return err
+		}
+
+		if !s.trackConn(conn) {
+			conn.Close()
+			return ErrServerClosed
+		}
+		go s.handleConn(conn)
+	}
+}
+
+// Close stops the listener, closes all open connections and waits for their goroutines.
+func (s *Server) Close() error {
+	s.connMu.Lock()
+	s.closed = true
+	var err error
+	if s.listener != nil {
+		err = s.listener.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.connMu.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) trackConn(conn net.Conn) bool {
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
+	if s.closed {
+		return false
+	}
+	s.conns[conn] = struct{}{}
+	s.wg.Add(1)
+
+	s.mu.Lock()
+	s.stats.currConnections++
+	s.stats.totalConnections++
+	s.mu.Unlock()
+	return true
+}
+
+func (s *Server) untrackConn(conn net.Conn) {
+	s.connMu.Lock()
+	delete(s.conns, conn)
+	s.connMu.Unlock()
+
+	s.mu.Lock()
+	s.stats.currConnections--
+	s.mu.Unlock()
+	s.wg.Done()
+}
+
+func (s *Server) handleConn(conn net.Conn) {
+	defer s.untrackConn(conn)
+	defer conn.Close()
+
+	r := bufio.NewReaderSize(conn, MaxLineLength)
+	w := bufio.NewWriter(conn)
+	for {
+		quit, err := s.serveCommand(r, w)
+		if err != nil || quit {
+			return
+		}
+		// flush only when the client has nothing more pipelined
+		if r.Buffered() == 0 {
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,410 @@
+package memcached
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// startServer runs a server on a loopback listener and closes it when the test ends.
+func startServer(t *testing.T, capacity int, opts ...Option) (*Server, string) {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := NewServer(capacity, opts...)
+	served := make(chan error, 1)
+	go func() { served <- s.Serve(l) }()
+
+	t.Cleanup(func() {
+		s.Close()
+		if err := <-served; !errors.Is(err, ErrServerClosed) {
+			t.Errorf("Serve returned %v, expected ErrServerClosed", err)
+		}
+	})
+	return s, l.Addr().String()
+}
+
+type client struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func dial(t *testing.T, addr string) *client {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &client{t: t, conn: conn, r: bufio.NewReader(conn)}
+}
+
+func (c *client) send(raw string) {
+	c.t.Helper()
+	if _, err := c.conn.Write([]byte(raw)); err != nil {
+		c.t.Fatalf("write: %v", err)
+	}
+}
+
+func (c *client) line() string {
+	c.t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		c.t.Fatalf("read: %v", err)
+	}
+	return strings.TrimRight(line, "\r\n")
+}
+
+// call sends a command and reads a single line reply.
+func (c *client) call(raw string) string {
+	c.t.Helper()
+	c.send(raw)
+	return c.line()
+}
+
+// get reads replies up to END, returns them without END.
+func (c *client) get(cmd string) []string {
+	c.t.Helper()
+	c.send(cmd + "\r\n")
+	var lines []string
+	for {
+		line := c.line()
+		if line == replyEnd {
+			return lines
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestServer_SetGet(t *testing.T) {
+	_, addr := startServer(t, 10)
+	c := dial(t, addr)
+
+	if got := c.call("set foo 42 0 5\r\nhello\r\n"); got != replyStored {
+		t.Fatalf("set = %q, expected STORED", got)
+	}
+
+	got := c.get("get foo missing")
+	expected := []string{"VALUE foo 42 5", "hello"}
+	if strings.Join(got, "|") != strings.Join(expected, "|") {
+		t.Errorf("get = %q, expected %q", got, expected)
+	}
+
+	if got := c.get("get missing"); len(got) != 0 {
+		t.Errorf("get missing = %q, expected nothing", got)
+	}
+}
+
+func TestServer_GetsReportsChangingCAS(t *testing.T) {
+	_, addr := startServer(t, 10)
+	c := dial(t, addr)
+
+	c.call("set k 0 0 1\r\na\r\n")
+	first := c.get("gets k")
+	c.call("set k 0 0 1\r\nb\r\n")
+	second := c.get("gets k")
+
+	if len(first) != 2 || len(second) != 2 {
+		t.Fatalf("gets = %q, %q", first, second)
+	}
+	var cas1, cas2 uint64
+	fmt.Sscanf(first[0], "VALUE k 0 1 %d", &cas1)
+	fmt.Sscanf(second[0], "VALUE k 0 1 %d", &cas2)
+	if cas1 == 0 || cas1 == cas2 {
+		t.Errorf("cas values %d, %d, expected different non-zero", cas1, cas2)
+	}
+}
+
+func TestServer_AddDelete(t *testing.T) {
+	_, addr := startServer(t, 10)
+	c := dial(t, addr)
+
+	if got := c.call("add k 0 0 1\r\na\r\n"); got != replyStored {
+		t.Errorf("first add = %q, expected STORED", got)
+	}
+	if got := c.call("add k 0 0 1\r\nb\r\n"); got != replyNotStored {
+		t.Errorf("second add = %q, expected NOT_STORED", got)
+	}
+	if got := c.get("get k"); len(got) != 2 || got[1] != "a" {
+		t.Errorf("get = %q, add must not overwrite", got)
+	}
+
+	if got := c.call("delete k\r\n"); got != replyDeleted {
+		t.Errorf("delete = %q, expected DELETED", got)
+	}
+	if got := c.call("delete k\r\n"); got != replyNotFound {
+		t.Errorf("second delete = %q, expected NOT_FOUND", got)
+	}
+}
+
+func TestServer_Incr(t *testing.T) {
+	_, addr := startServer(t, 10)
+	c := dial(t, addr)
+
+	if got := c.call("incr n 1\r\n"); got != replyNotFound {
+		t.Errorf("incr missing = %q, expected NOT_FOUND", got)
+	}
+
+	c.call("set n 7 0 2\r\n10\r\n")
+	if got := c.call("incr n 5\r\n"); got != "15" {
+		t.Errorf("incr = %q, expected 15", got)
+	}
+	if got := c.get("get n"); len(got) != 2 || got[0] != "VALUE n 7 2" || got[1] != "15" {
+		t.Errorf("get after incr = %q", got)
+	}
+
+	c.call("set max 0 0 20\r\n18446744073709551615\r\n")
+	if got := c.call("incr max 2\r\n"); got != "1" {
+		t.Errorf("incr overflow = %q, expected wrap to 1", got)
+	}
+
+	c.call("set s 0 0 3\r\nabc\r\n")
+	if got := c.call("incr s 1\r\n"); got != clientErrorNumeric {
+		t.Errorf("incr non-numeric = %q", got)
+	}
+	if got := c.call("incr n -1\r\n"); got != clientErrorDelta {
+		t.Errorf("incr bad delta = %q", got)
+	}
+}
+
+func TestServer_ExpirationAndTouch(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1_700_000_000, 0)}
+	_, addr := startServer(t, 10, WithClock(clock.Now))
+	c := dial(t, addr)
+
+	c.call("set short 0 10 1\r\na\r\n")
+	c.call("set touched 0 10 1\r\nb\r\n")
+	c.call(fmt.Sprintf("set absolute 0 %d 1\r\nc\r\n", clock.Now().Unix()+10))
+	c.call("set gone 0 -1 1\r\nd\r\n")
+
+	if got := c.get("get gone"); len(got) != 0 {
+		t.Errorf("negative exptime stored: %q", got)
+	}
+	if got := c.call("touch touched 100\r\n"); got != replyTouched {
+		t.Errorf("touch = %q, expected TOUCHED", got)
+	}
+	if got := c.call("touch missing 100\r\n"); got != replyNotFound {
+		t.Errorf("touch missing = %q, expected NOT_FOUND", got)
+	}
+
+	clock.Advance(20 * time.Second)
+
+	if got := c.get("get short absolute"); len(got) != 0 {
+		t.Errorf("expired keys returned: %q", got)
+	}
+	if got := c.get("get touched"); len(got) != 2 {
+		t.Errorf("touched key expired: %q", got)
+	}
+}
+
+func TestServer_IncrKeepsTTL(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1_700_000_000, 0)}
+	_, addr := startServer(t, 10, WithClock(clock.Now))
+	c := dial(t, addr)
+
+	c.call("set n 0 10 1\r\n1\r\n")
+	clock.Advance(5 * time.Second)
+	c.call("incr n 1\r\n")
+	clock.Advance(6 * time.Second)
+
+	if got := c.get("get n"); len(got) != 0 {
+		t.Errorf("incr extended TTL: %q", got)
+	}
+}
+
+func TestServer_ProtocolErrors(t *testing.T) {
+	_, addr := startServer(t, 10)
+	c := dial(t, addr)
+
+	if got := c.call("bogus\r\n"); got != replyError {
+		t.Errorf("unknown command = %q, expected ERROR", got)
+	}
+	if got := c.call("set k x 0 1\r\n"); got != clientErrorFormat {
+		t.Errorf("bad flags = %q, expected %q", got, clientErrorFormat)
+	}
+	if got := c.call("set " + strings.Repeat("k", MaxKeyLength+1) + " 0 0 1\r\n"); got != clientErrorFormat {
+		t.Errorf("long key = %q, expected %q", got, clientErrorFormat)
+	}
+	if got := c.call(fmt.Sprintf("set big 0 0 %d\r\n%s\r\n", MaxValueSize+1, strings.Repeat("x", MaxValueSize+1))); got != serverErrorTooBig {
+		t.Errorf("big value = %q, expected %q", got, serverErrorTooBig)
+	}
+	// connection stays usable after errors
+	if got := c.call("set k 0 0 1\r\nv\r\n"); got != replyStored {
+		t.Errorf("set after errors = %q, expected STORED", got)
+	}
+}
+
+func TestServer_BadDataChunkDropsConnection(t *testing.T) {
+	_, addr := startServer(t, 10)
+	c := dial(t, addr)
+
+	if got := c.call("set k 0 0 3\r\nabcXY"); got != clientErrorChunk {
+		t.Errorf("bad chunk = %q, expected %q", got, clientErrorChunk)
+	}
+	c.conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := c.r.ReadString('\n'); err == nil {
+		t.Error("connection still open after a bad data chunk")
+	}
+}
+
+func TestServer_LineTooLong(t *testing.T) {
+	_, addr := startServer(t, 10)
+	c := dial(t, addr)
+
+	// no newline at all: the server must not wait for one forever
+	if got := c.call("get " + strings.Repeat("k", MaxLineLength)); got != clientErrorLine {
+		t.Errorf("long line = %q, expected %q", got, clientErrorLine)
+	}
+	c.conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := c.r.ReadString('\n'); err == nil {
+		t.Error("connection still open after a too long line")
+	}
+
+	c = dial(t, addr)
+	if got := c.call("get " + strings.Repeat("k", MaxLineLength-10) + "\r\n"); got != replyEnd {
+		t.Errorf("line just under the limit = %q, expected END", got)
+	}
+}
+
+func TestServer_NoreplyAndPipelining(t *testing.T) {
+	_, addr := startServer(t, 10)
+	c := dial(t, addr)
+
+	c.send("set a 0 0 1 noreply\r\n1\r\nset b 0 0 1 noreply\r\n2\r\ndelete a noreply\r\nget a b\r\n")
+
+	got := []string{c.line(), c.line(), c.line()}
+	expected := []string{"VALUE b 0 1", "2", replyEnd}
+	if strings.Join(got, "|") != strings.Join(expected, "|") {
+		t.Errorf("pipelined reply = %q, expected %q", got, expected)
+	}
+}
+
+func TestServer_EvictsLeastFrequentlyUsed(t *testing.T) {
+	_, addr := startServer(t, 2)
+	c := dial(t, addr)
+
+	c.call("set hot 0 0 1\r\nh\r\n")
+	c.call("set cold 0 0 1\r\nc\r\n")
+	c.get("get hot")
+	c.call("set new 0 0 1\r\nn\r\n")
+
+	if got := c.get("get cold"); len(got) != 0 {
+		t.Errorf("cold should be evicted, got %q", got)
+	}
+	if got := c.get("get hot new"); len(got) != 4 {
+		t.Errorf("get hot new = %q, expected both", got)
+	}
+}
+
+func TestServer_Stats(t *testing.T) {
+	_, addr := startServer(t, 10)
+	c := dial(t, addr)
+
+	c.call("set a 0 0 1\r\n1\r\n")
+	c.get("get a")
+	c.get("get missing")
+
+	stats := make(map[string]string)
+	for _, line := range c.get("stats") {
+		var name, value string
+		fmt.Sscanf(line, "STAT %s %s", &name, &value)
+		stats[name] = value
+	}
+
+	expected := map[string]string{
+		"cmd_set": "1", "cmd_get": "2", "get_hits": "1", "get_misses": "1",
+		"curr_items": "1", "curr_connections": "1",
+	}
+	for name, value := range expected {
+		if stats[name] != value {
+			t.Errorf("stat %s = %q, expected %q", name, stats[name], value)
+		}
+	}
+}
+
+func TestServer_ConcurrentClients(t *testing.T) {
+	_, addr := startServer(t, 1000)
+
+	setup := dial(t, addr)
+	setup.call("set counter 0 0 1\r\n0\r\n")
+
+	const clients, increments = 10, 50
+	var wg sync.WaitGroup
+	for i := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := net.Dial("tcp", addr)
+			if err != nil {
+				t.Errorf("dial: %v", err)
+				return
+			}
+			defer conn.Close()
+			r := bufio.NewReader(conn)
+
+			for j := range increments {
+				fmt.Fprintf(conn, "set k%d-%d 0 0 1\r\nx\r\nincr counter 1\r\n", i, j)
+				for range 2 {
+					if _, err := r.ReadString('\n'); err != nil {
+						t.Errorf("read: %v", err)
+						return
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := setup.get("get counter"); len(got) != 2 || got[1] != fmt.Sprint(clients*increments) {
+		t.Errorf("counter = %q, expected %d (incr must be atomic)", got, clients*increments)
+	}
+}
+
+func TestServer_CloseDropsConnections(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := NewServer(10)
+	served := make(chan error, 1)
+	go func() { served <- s.Serve(l) }()
+
+	c := dial(t, l.Addr().String())
+	c.call("set a 0 0 1\r\n1\r\n")
+
+	s.Close()
+	if err := <-served; !errors.Is(err, ErrServerClosed) {
+		t.Errorf("Serve = %v, expected ErrServerClosed", err)
+	}
+	c.conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := c.r.ReadString('\n'); err == nil {
+		t.Error("connection still open after Close")
+	}
+}